@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// envelope is the --format json output for one document: the converted
+// text and blocks with the warnings and stats that go with them, and the
+// --embed-source metadata
+type envelope struct {
+	Text     string             `json:"text"`
+	Blocks   []slackify.Block   `json:"blocks"`
+	Warnings []string           `json:"warnings"`
+	Stats    envelopeStats      `json:"stats"`
+	Metadata *slackify.Metadata `json:"metadata,omitempty"`
+}
+
+// envelopeStats are the --stats figures for one document
+type envelopeStats struct {
+	Characters int            `json:"characters"`
+	Words      int            `json:"words"`
+	Blocks     map[string]int `json:"blocks"`
+	Messages   int            `json:"messages"`
+}
+
+// jsonEnvelope returns the --format json output for markdown converted to
+// text and blocks
+func jsonEnvelope(markdown, text string, blocks []slackify.Block, warnings []string, meta *slackify.Metadata) (string, error) {
+	env := envelope{
+		Text:     text,
+		Blocks:   blocks,
+		Warnings: warnings,
+		Metadata: meta,
+		Stats: envelopeStats{
+			Characters: utf8.RuneCountInString(text),
+			Words:      len(strings.Fields(text)),
+			Blocks:     countBlocks(markdown),
+			Messages:   len(slackify.Split(text, slackify.MessageLimit)),
+		},
+	}
+	if env.Blocks == nil {
+		env.Blocks = []slackify.Block{}
+	}
+	if env.Warnings == nil {
+		env.Warnings = []string{}
+	}
+
+	// Slack's <url|text> links read better unescaped
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(env); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// withMetadata adds metadata to a rich_text message payload, which Slack
+// keeps with the message without showing it
+func withMetadata(payload string, meta slackify.Metadata) (string, error) {
+	var message struct {
+		Blocks   json.RawMessage   `json:"blocks"`
+		Metadata slackify.Metadata `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		return "", err
+	}
+	message.Metadata = meta
+	data, err := json.MarshalIndent(message, "", "  ")
+	return string(data), err
+}
+
+// writeSourceFile writes the --embed-source metadata of the documents in an
+// output file next to it, one JSON line per document
+func writeSourceFile(path string, sources []*slackify.Metadata) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	for _, meta := range sources {
+		if err := encoder.Encode(meta); err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error encoding source metadata: %v\n"), err)
+			os.Exit(exitParse)
+		}
+	}
+	if err := os.WriteFile(path+".source.json", b.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error writing source metadata: %v\n"), err)
+		os.Exit(exitIO)
+	}
+}
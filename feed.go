@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// feedDocument holds the entries of an RSS 2.0 or Atom feed
+type feedDocument struct {
+	Items []struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		GUID        string `xml:"guid"`
+		PubDate     string `xml:"pubDate"`
+		Description string `xml:"description"`
+		Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	} `xml:"channel>item"`
+	Entries []struct {
+		Title     string `xml:"title"`
+		ID        string `xml:"id"`
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+		Links     []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary string `xml:"summary"`
+		Content string `xml:"content"`
+	} `xml:"entry"`
+}
+
+// feedEntry is one feed item, whichever format it came from
+type feedEntry struct {
+	id, title, link, content string
+}
+
+// parseFeed reads the entries of an RSS or Atom feed, in feed order
+func parseFeed(data []byte) ([]feedEntry, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "iso-8859-1", "latin1":
+			data, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			return strings.NewReader(decodeLatin1(data)), nil
+		case "windows-1252":
+			data, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			return strings.NewReader(decodeWindows1252(data)), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	var doc feedDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var entries []feedEntry
+	for _, item := range doc.Items {
+		entry := feedEntry{item.GUID, item.Title, item.Link, item.Content}
+		if entry.content == "" {
+			entry.content = item.Description
+		}
+		if entry.id == "" {
+			entry.id = item.Link
+		}
+		if entry.id == "" {
+			entry.id = contentID(entry.title, item.PubDate, entry.content)
+		}
+		entries = append(entries, entry)
+	}
+	for _, atom := range doc.Entries {
+		entry := feedEntry{atom.ID, atom.Title, "", atom.Content}
+		for _, link := range atom.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				entry.link = link.Href
+				break
+			}
+		}
+		if entry.content == "" {
+			entry.content = atom.Summary
+		}
+		if entry.id == "" {
+			entry.id = entry.link
+		}
+		if entry.id == "" {
+			date := atom.Published
+			if date == "" {
+				date = atom.Updated
+			}
+			entry.id = contentID(entry.title, date, entry.content)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// contentID identifies an entry with neither an ID nor a link by a hash of
+// its title, date and content, so it is still only converted once
+func contentID(title, date, content string) string {
+	sum := sha256.Sum256([]byte(title + "\n" + strings.TrimSpace(date) + "\n" + content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// readFeedState returns the IDs of the entries already converted, one per
+// line of path; a missing file means none
+func readFeedState(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// writeFeedState records seen entry IDs in path, keeping the newest 1000
+func writeFeedState(path string, seen []string) error {
+	if len(seen) > 1000 {
+		seen = seen[len(seen)-1000:]
+	}
+	return os.WriteFile(path, []byte(strings.Join(seen, "\n")+"\n"), 0644)
+}
+
+// runFeed implements the feed subcommand: it converts the newest entries of
+// an RSS or Atom feed
+func runFeed(args []string) {
+	flags := flag.NewFlagSet("feed", flag.ExitOnError)
+	limit := flags.Int("limit", 5, "Number of newest entries to convert")
+	statePath := flags.String("state", "", "File recording converted entries, so later runs only print new ones")
+	timeout := flags.Duration("timeout", 30*time.Second, "Time limit for fetching the feed")
+	var outputFile string
+	flags.StringVar(&outputFile, "o", "", "Output file (default: stdout)")
+	flags.StringVar(&outputFile, "output", "", "Output file (default: stdout)")
+	var separator string
+	flags.StringVar(&separator, "separator", "\n\n---\n\n", "Text placed between converted entries (\\n is a newline)")
+	var opts slackify.Options
+	conversionFlags(flags, &opts)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, tr("Usage: %s feed [options] URL\n\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("Convert the newest entries of an RSS or Atom feed to Slack formatting\n\n"))
+		fmt.Fprintf(os.Stderr, tr("Options:\n"))
+		translateUsage(flags)
+		flags.PrintDefaults()
+	}
+
+	positional := parseInterspersed(flags, args)
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	applyPreset(flags)
+	checkOptions(opts)
+	applyUsergroups(flags, &opts)
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error fetching feed: %v\n"), err)
+		os.Exit(exitIO)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("server returned %s", resp.Status)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error fetching feed: %v\n"), err)
+		os.Exit(exitIO)
+	}
+
+	entries, err := parseFeed(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error parsing feed: %v\n"), err)
+		os.Exit(exitParse)
+	}
+
+	var seen []string
+	if *statePath != "" {
+		seen, err = readFeedState(*statePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error reading state file: %v\n"), err)
+			os.Exit(exitIO)
+		}
+	}
+
+	converter := slackify.New(slackify.WithOptions(opts))
+	var converted []string
+	for _, entry := range entries {
+		if len(converted) == *limit {
+			break
+		}
+		if slices.Contains(seen, entry.id) {
+			continue
+		}
+
+		// Feed content is usually HTML, but some feeds carry markdown
+		content := strings.TrimSpace(entry.content)
+		if strings.HasPrefix(content, "<") {
+			content, err = slackify.ToMarkdown(content, "html")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, tr("Error parsing entry %q: %v\n"), entry.title, err)
+				os.Exit(exitParse)
+			}
+		}
+
+		markdown := fmt.Sprintf("# %s\n\n%s", entry.title, content)
+		if entry.link != "" {
+			markdown += fmt.Sprintf("\n\n[Read more](%s)", entry.link)
+		}
+		converted = append(converted, converter.Convert(markdown))
+		seen = append(seen, entry.id)
+	}
+
+	slackText := strings.Join(converted, strings.ReplaceAll(separator, "\\n", "\n"))
+	if outputFile != "" {
+		writeOutputFile(outputFile, slackText)
+	} else if slackText != "" {
+		fmt.Print(slackText)
+	}
+
+	if *statePath != "" {
+		if err := writeFeedState(*statePath, seen); err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error writing state file: %v\n"), err)
+			os.Exit(exitIO)
+		}
+	}
+}
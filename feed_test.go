@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+		want []feedEntry
+	}{
+		{
+			"rss",
+			`<rss><channel>
+<item><title>One</title><link>https://example.com/1</link><guid>id-1</guid><description>Summary</description></item>
+<item><title>Two</title><link>https://example.com/2</link><description>&lt;p&gt;HTML&lt;/p&gt;</description></item>
+</channel></rss>`,
+			[]feedEntry{
+				{"id-1", "One", "https://example.com/1", "Summary"},
+				{"https://example.com/2", "Two", "https://example.com/2", "<p>HTML</p>"},
+			},
+		},
+		{
+			"rss content encoded",
+			`<rss xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel>
+<item><title>One</title><guid>id-1</guid><description>Short</description><content:encoded>Full</content:encoded></item>
+</channel></rss>`,
+			[]feedEntry{{"id-1", "One", "", "Full"}},
+		},
+		{
+			"atom",
+			`<feed xmlns="http://www.w3.org/2005/Atom">
+<entry><title>One</title><id>urn:1</id><link rel="self" href="https://example.com/self"/><link href="https://example.com/1"/><summary>Summary</summary></entry>
+<entry><title>Two</title><link rel="alternate" href="https://example.com/2"/><content>Body</content></entry>
+</feed>`,
+			[]feedEntry{
+				{"urn:1", "One", "https://example.com/1", "Summary"},
+				{"https://example.com/2", "Two", "https://example.com/2", "Body"},
+			},
+		},
+		{
+			"windows-1252",
+			"<?xml version=\"1.0\" encoding=\"windows-1252\"?><rss><channel><item><title>\x93Quoted\x94 \x80</title><guid>id-1</guid></item></channel></rss>",
+			[]feedEntry{{"id-1", "“Quoted” €", "", ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed([]byte(tt.feed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFeed = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedContentID(t *testing.T) {
+	feed := func(title, date, body string) string {
+		return "<rss><channel><item><title>" + title + "</title><pubDate>" + date + "</pubDate><description>" + body + "</description></item></channel></rss>"
+	}
+	id := func(data string) string {
+		entries, err := parseFeed([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries[0].id
+	}
+
+	first := id(feed("News", "Mon, 01 Jan 2024", "Body"))
+	if !strings.HasPrefix(first, "sha256:") {
+		t.Fatalf("entry without guid or link has id %q, want a content hash", first)
+	}
+	tests := []struct {
+		name      string
+		feed      string
+		duplicate bool
+	}{
+		{"same entry", feed("News", "Mon, 01 Jan 2024", "Body"), true},
+		{"date padded", feed("News", " Mon, 01 Jan 2024 ", "Body"), true},
+		{"other title", feed("Update", "Mon, 01 Jan 2024", "Body"), false},
+		{"other date", feed("News", "Tue, 02 Jan 2024", "Body"), false},
+		{"other body", feed("News", "Mon, 01 Jan 2024", "Other"), false},
+	}
+	for _, tt := range tests {
+		if got := id(tt.feed) == first; got != tt.duplicate {
+			t.Errorf("%s: same id = %v, want %v", tt.name, got, tt.duplicate)
+		}
+	}
+}
+
+func TestFeedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+
+	seen, err := readFeedState(path)
+	if err != nil || seen != nil {
+		t.Fatalf("readFeedState of a missing file = %v, %v, want nothing", seen, err)
+	}
+
+	ids := []string{"id-1", "https://example.com/2", "sha256:abc"}
+	if err := writeFeedState(path, ids); err != nil {
+		t.Fatal(err)
+	}
+	if seen, err = readFeedState(path); err != nil || !slices.Equal(seen, ids) {
+		t.Errorf("readFeedState = %v, %v, want %v", seen, err, ids)
+	}
+
+	var many []string
+	for i := 0; i < 1005; i++ {
+		many = append(many, "id-"+strconv.Itoa(i))
+	}
+	if err := writeFeedState(path, many); err != nil {
+		t.Fatal(err)
+	}
+	if seen, err = readFeedState(path); err != nil || !slices.Equal(seen, many[5:]) {
+		t.Errorf("readFeedState after writing %d ids kept %d, want the newest 1000", len(many), len(seen))
+	}
+
+	if err := os.WriteFile(path, []byte("a\n\nb  c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if seen, err = readFeedState(path); err != nil || !slices.Equal(seen, []string{"a", "b", "c"}) {
+		t.Errorf("readFeedState = %v, %v, want [a b c]", seen, err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// githubAPI is the base URL of the GitHub REST API
+const githubAPI = "https://api.github.com"
+
+// githubGet fetches path from the GitHub API and decodes the JSON response
+// into v. A token in GITHUB_TOKEN or GH_TOKEN is sent when set, for private
+// repositories and higher rate limits.
+func githubGet(path string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, githubAPI+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+			break
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub returned %s for %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// parseInterspersed parses args with flags allowed before and after the
+// positional arguments, which it returns
+func parseInterspersed(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		if flags.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// validRepo reports whether repo looks like owner/name
+func validRepo(repo string) bool {
+	owner, name, ok := strings.Cut(repo, "/")
+	return ok && owner != "" && name != "" && !strings.Contains(name, "/")
+}
+
+// runRelease implements the release subcommand: it converts the notes of a
+// GitHub release
+func runRelease(args []string) {
+	flags := flag.NewFlagSet("release", flag.ExitOnError)
+	tag := flags.String("tag", "", "Release tag (default: the latest release)")
+	var outputFile string
+	flags.StringVar(&outputFile, "o", "", "Output file (default: stdout)")
+	flags.StringVar(&outputFile, "output", "", "Output file (default: stdout)")
+	var opts slackify.Options
+	conversionFlags(flags, &opts)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, tr("Usage: %s release [options] owner/repo\n\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("Convert the notes of a GitHub release to Slack formatting\n\n"))
+		fmt.Fprintf(os.Stderr, tr("Options:\n"))
+		translateUsage(flags)
+		flags.PrintDefaults()
+	}
+
+	positional := parseInterspersed(flags, args)
+	if len(positional) != 1 || !validRepo(positional[0]) {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	applyPreset(flags)
+	checkOptions(opts)
+	applyUsergroups(flags, &opts)
+	repo := positional[0]
+
+	path := "/repos/" + repo + "/releases/latest"
+	if *tag != "" {
+		path = "/repos/" + repo + "/releases/tags/" + url.PathEscape(*tag)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		Name    string `json:"name"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := githubGet(path, &release); err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error fetching release: %v\n"), err)
+		os.Exit(exitIO)
+	}
+
+	title := release.Name
+	if title == "" {
+		title = release.TagName
+	}
+	markdown := fmt.Sprintf("# %s\n\n%s\n\n[View on GitHub](%s)", title, strings.TrimSpace(release.Body), release.HTMLURL)
+	slackText := slackify.Convert(markdown, opts)
+
+	if outputFile != "" {
+		writeOutputFile(outputFile, slackText)
+		return
+	}
+	fmt.Print(slackText)
+}
+
+// runIssue implements the gh-issue subcommand: it converts the description
+// of a GitHub issue or pull request, and optionally its comments
+func runIssue(args []string) {
+	flags := flag.NewFlagSet("gh-issue", flag.ExitOnError)
+	comments := flags.Bool("comments", false, "Include the comments after the description")
+	var outputFile string
+	flags.StringVar(&outputFile, "o", "", "Output file (default: stdout)")
+	flags.StringVar(&outputFile, "output", "", "Output file (default: stdout)")
+	var opts slackify.Options
+	conversionFlags(flags, &opts)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, tr("Usage: %s gh-issue [options] owner/repo#123\n\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("Convert the description of a GitHub issue or pull request to Slack formatting\n\n"))
+		fmt.Fprintf(os.Stderr, tr("Options:\n"))
+		translateUsage(flags)
+		flags.PrintDefaults()
+	}
+
+	positional := parseInterspersed(flags, args)
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	repo, number, ok := strings.Cut(positional[0], "#")
+	if _, err := strconv.Atoi(number); !ok || err != nil || !validRepo(repo) {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid issue '%s' (use owner/repo#123)\n"), positional[0])
+		os.Exit(exitUsage)
+	}
+	applyPreset(flags)
+	checkOptions(opts)
+	applyUsergroups(flags, &opts)
+
+	type githubUser struct {
+		Login string `json:"login"`
+	}
+	var issue struct {
+		Title       string     `json:"title"`
+		Body        string     `json:"body"`
+		State       string     `json:"state"`
+		HTMLURL     string     `json:"html_url"`
+		User        githubUser `json:"user"`
+		PullRequest *struct{}  `json:"pull_request"`
+	}
+	path := "/repos/" + repo + "/issues/" + number
+	if err := githubGet(path, &issue); err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error fetching issue: %v\n"), err)
+		os.Exit(exitIO)
+	}
+
+	kind := "Issue"
+	if issue.PullRequest != nil {
+		kind = "Pull request"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# #%s %s\n\n", number, issue.Title)
+	fmt.Fprintf(&b, "%s opened by @%s, %s\n\n", kind, issue.User.Login, issue.State)
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		fmt.Fprintf(&b, "%s\n\n", body)
+	}
+	fmt.Fprintf(&b, "[View on GitHub](%s)", issue.HTMLURL)
+
+	if *comments {
+		var list []struct {
+			Body string     `json:"body"`
+			User githubUser `json:"user"`
+		}
+		if err := githubGet(path+"/comments?per_page=100", &list); err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error fetching comments: %v\n"), err)
+			os.Exit(exitIO)
+		}
+		for _, comment := range list {
+			fmt.Fprintf(&b, "\n\n---\n\n**@%s** commented:\n\n%s", comment.User.Login, strings.TrimSpace(comment.Body))
+		}
+	}
+
+	slackText := slackify.Convert(b.String(), opts)
+	if outputFile != "" {
+		writeOutputFile(outputFile, slackText)
+		return
+	}
+	fmt.Print(slackText)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseGlossary reads a glossary file: a flat YAML mapping of terms to their
+// replacements, one "term: replacement" per line, with # comments and
+// optionally quoted keys and values
+func parseGlossary(data string) (map[string]string, error) {
+	unquote := func(s string) (string, error) {
+		s = strings.TrimSpace(s)
+		var value, rest string
+		switch {
+		case strings.HasPrefix(s, `"`):
+			prefix, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return "", err
+			}
+			value, _ = strconv.Unquote(prefix)
+			rest = s[len(prefix):]
+		case strings.HasPrefix(s, "'"):
+			// '' is an escaped quote inside single quotes
+			end := strings.Index(strings.ReplaceAll(s[1:], "''", "__"), "'")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote")
+			}
+			value = strings.ReplaceAll(s[1:end+1], "''", "'")
+			rest = s[end+2:]
+		default:
+			// An unquoted value ends at a comment
+			if i := strings.Index(s, " #"); i >= 0 {
+				s = strings.TrimSpace(s[:i])
+			}
+			return s, nil
+		}
+
+		// Only a comment may follow a quoted string
+		if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after quoted string", rest)
+		}
+		return value, nil
+	}
+
+	glossary := map[string]string{}
+	for n, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		// A quoted key may itself contain a colon
+		var key, value string
+		var ok bool
+		if strings.HasPrefix(trimmed, `"`) || strings.HasPrefix(trimmed, "'") {
+			if end := strings.IndexByte(trimmed[1:], trimmed[0]); end >= 0 {
+				key = trimmed[:end+2]
+				value, ok = strings.CutPrefix(strings.TrimSpace(trimmed[end+2:]), ":")
+			}
+		} else {
+			key, value, ok = strings.Cut(trimmed, ": ")
+			if !ok && strings.HasSuffix(trimmed, ":") {
+				key, ok = strings.TrimSuffix(trimmed, ":"), true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"term: replacement\"", n+1)
+		}
+
+		term, err := unquote(key)
+		if err == nil {
+			value, err = unquote(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		if term == "" {
+			return nil, fmt.Errorf("line %d: empty term", n+1)
+		}
+		glossary[term] = value
+	}
+	return glossary, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// runLint implements the lint subcommand: it reports problems in text
+// already written for Slack
+func runLint(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	target := flags.String("target", "slack", "Markup the input is written in: slack for mrkdwn")
+	flags.String("lang", "", "Language of messages, like de, or a JSON file mapping English messages to translations (default: $SLACKIFY_LANG or English)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, tr("Usage: %s lint [options] [file...]\n\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("Report unbalanced formatting, unescaped &, < and >, malformed mentions and over-long text in Slack mrkdwn\n\n"))
+		fmt.Fprintf(os.Stderr, tr("Options:\n"))
+		translateUsage(flags)
+		flags.PrintDefaults()
+	}
+
+	positional := parseInterspersed(flags, args)
+	if *target != "slack" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --target value '%s' (use slack)\n"), *target)
+		os.Exit(exitUsage)
+	}
+
+	var documents []document
+	for _, inputFile := range positional {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error: File '%s' not found: %v\n"), inputFile, err)
+			os.Exit(exitIO)
+		}
+		text, err := readInput(file, "utf-8")
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error reading '%s': %v\n"), inputFile, err)
+			os.Exit(exitIO)
+		}
+		documents = append(documents, document{name: inputFile, line: 1, text: text})
+	}
+	if len(positional) == 0 {
+		text, err := readInput(os.Stdin, "utf-8")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error reading input: %v\n"), err)
+			os.Exit(exitIO)
+		}
+		documents = append(documents, document{name: "stdin", line: 1, text: text})
+	}
+
+	problems := 0
+	for _, doc := range documents {
+		for _, w := range slackify.Lint(doc.text) {
+			fmt.Printf("%s:%d:%d: %s [%s]\n", doc.name, w.Line, w.Column, w.Message, w.Code)
+			problems++
+		}
+	}
+	if problems > 0 {
+		os.Exit(exitLint)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// localeFiles are the translations shipped with the tool, one JSON object
+// per language mapping English messages to translated ones
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalog holds the translations of the chosen language, nil for English
+var catalog map[string]string
+
+// setLanguage chooses the language of messages: a code like de or
+// de_DE.UTF-8, or the path of a JSON file of translations. English, or an
+// empty lang, keeps the messages as written. Messages without a translation
+// stay in English.
+func setLanguage(lang string) error {
+	var data []byte
+	var err error
+	if strings.HasSuffix(lang, ".json") {
+		data, err = os.ReadFile(lang)
+	} else {
+		code, _, _ := strings.Cut(strings.ToLower(lang), ".")
+		code, _, _ = strings.Cut(code, "_")
+		code, _, _ = strings.Cut(code, "-")
+		if code == "" || code == "en" || code == "c" || code == "posix" {
+			catalog = nil
+			return nil
+		}
+		data, err = localeFiles.ReadFile("locales/" + code + ".json")
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no translation for %s", code)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	var translations map[string]string
+	if err := json.Unmarshal(data, &translations); err != nil {
+		return err
+	}
+	catalog = translations
+	return nil
+}
+
+// scanLanguage sets the language from a --lang flag in args, before the
+// flags are parsed, so that usage messages printed while parsing them are
+// translated too. It exits with a usage error when there is no such
+// language.
+func scanLanguage(args []string) {
+	lang, found := "", false
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "lang" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value, hasValue = args[i+1], true
+		}
+		if hasValue {
+			lang, found = value, true
+		}
+	}
+	if !found || lang == "" {
+		return
+	}
+	if err := setLanguage(lang); err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --lang value '%s': %v (use a language like de, or a JSON file of translations)\n"), lang, err)
+		os.Exit(exitUsage)
+	}
+}
+
+// tr returns message in the chosen language, or as written when it has no
+// translation
+func tr(message string) string {
+	if translated, ok := catalog[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// translateUsage translates the help text of the flags of flags, for usage
+// messages
+func translateUsage(flags *flag.FlagSet) {
+	flags.VisitAll(func(f *flag.Flag) {
+		f.Usage = tr(f.Usage)
+	})
+}
@@ -2,300 +2,867 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
+	"os/user"
+	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// Exit codes, so wrapper scripts can tell what went wrong without parsing
+// stderr
+const (
+	exitUsage   = 2 // invalid flags, arguments or missing input
+	exitIO      = 3 // reading input or writing output failed
+	exitParse   = 4 // the input could not be parsed or converted
+	exitTooLong = 5 // the output is longer than --max-length
+	exitSlack   = 6 // a Slack API request failed
+	exitSecrets = 7 // the input contains credentials and --fail-on-secrets is set
+	exitWarning = 8 // converting warned and --fail-on-warning is set
+	exitLint    = 9 // lint found problems in the input
 )
 
-// TableFormatter handles markdown table conversion
-type TableFormatter struct {
-	lines []string
+// decodeInput turns raw input bytes into a string. Latin-1 and Windows-1252
+// input is converted to UTF-8; "auto" converts Latin-1 only when the bytes
+// are not valid UTF-8.
+func decodeInput(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case "latin1":
+		return decodeLatin1(data), nil
+	case "windows-1252":
+		return decodeWindows1252(data), nil
+	case "auto":
+		if !utf8.Valid(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))) {
+			return decodeLatin1(data), nil
+		}
+		return string(data), nil
+	case "utf-8", "":
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q", encoding)
 }
 
-// convertTables converts markdown tables to Slack-friendly format
-func convertTables(text string) string {
-	lines := strings.Split(text, "\n")
-	result := []string{}
-	i := 0
-
-	for i < len(lines) {
-		line := strings.TrimSpace(lines[i])
-
-		// Check if this line looks like a table header
-		if strings.Contains(line, "|") && strings.HasPrefix(line, "|") && strings.HasSuffix(line, "|") {
-			// Found potential table start
-			tableLines := []string{}
-			j := i
-
-			// Collect all table lines
-			for j < len(lines) {
-				currentLine := strings.TrimSpace(lines[j])
-				if strings.Contains(currentLine, "|") && (strings.HasPrefix(currentLine, "|") || strings.Contains(currentLine, "|")) {
-					tableLines = append(tableLines, currentLine)
-					j++
-				} else if currentLine == "" {
-					// Empty line might be part of table formatting
-					if j+1 < len(lines) && strings.Contains(lines[j+1], "|") {
-						tableLines = append(tableLines, currentLine)
-						j++
-					} else {
-						break
-					}
-				} else {
-					break
+// decodeLatin1 maps each ISO-8859-1 byte to the Unicode code point of the same value
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252, where it differs
+// from Latin-1, to Unicode. The five bytes it leaves undefined keep their
+// Latin-1 code points.
+var windows1252 = [32]rune{
+	'\u20ac', '\u0081', '\u201a', '\u0192', '\u201e', '\u2026', '\u2020', '\u2021',
+	'\u02c6', '\u2030', '\u0160', '\u2039', '\u0152', '\u008d', '\u017d', '\u008f',
+	'\u0090', '\u2018', '\u2019', '\u201c', '\u201d', '\u2022', '\u2013', '\u2014',
+	'\u02dc', '\u2122', '\u0161', '\u203a', '\u0153', '\u009d', '\u017e', '\u0178',
+}
+
+// decodeWindows1252 maps each Windows-1252 byte to its Unicode code point
+func decodeWindows1252(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		if c >= 0x80 && c <= 0x9f {
+			runes[i] = windows1252[c-0x80]
+		} else {
+			runes[i] = rune(c)
+		}
+	}
+	return string(runes)
+}
+
+// readInput reads a whole markdown document, decoding it and normalizing its
+// line endings
+func readInput(reader io.Reader, encoding string) (string, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := decodeInput(data, encoding)
+	if err != nil {
+		return "", err
+	}
+	// Like a line scanner, drop the final line ending; Convert normalizes the rest
+	text = strings.TrimSuffix(text, "\n")
+	return strings.TrimSuffix(text, "\r"), nil
+}
+
+// jsonLinesRequest is one line of JSON Lines input
+type jsonLinesRequest struct {
+	ID       json.RawMessage `json:"id"`
+	Markdown *string         `json:"markdown"`
+}
+
+// jsonLinesResponse is one line of JSON Lines output
+type jsonLinesResponse struct {
+	ID       json.RawMessage `json:"id"`
+	Mrkdwn   string          `json:"mrkdwn"`
+	Warnings []string        `json:"warnings"`
+}
+
+// convertJSONLines converts a stream of JSON Lines requests, writing one
+// response line per request as soon as it is converted. Malformed lines get a
+// response carrying a warning instead of stopping the stream. warned tells
+// whether any response carried a warning.
+func convertJSONLines(r io.Reader, w io.Writer, converter *slackify.Converter) (warned bool, err error) {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var req jsonLinesRequest
+			resp := jsonLinesResponse{Warnings: []string{}}
+
+			if jsonErr := json.Unmarshal(line, &req); jsonErr != nil {
+				resp.Warnings = append(resp.Warnings, fmt.Sprintf("invalid JSON: %v", jsonErr))
+			} else if req.Markdown == nil {
+				resp.Warnings = append(resp.Warnings, "missing \"markdown\" field")
+			} else {
+				resp.Mrkdwn = converter.Convert(*req.Markdown)
+				for _, w := range converter.Check(*req.Markdown) {
+					resp.Warnings = append(resp.Warnings, w.String())
+				}
+				for _, w := range converter.Secrets(*req.Markdown) {
+					resp.Warnings = append(resp.Warnings, w.String())
 				}
 			}
+			resp.ID = req.ID
+			if resp.ID == nil {
+				resp.ID = json.RawMessage("null")
+			}
 
-			if len(tableLines) >= 2 { // At least header + separator
-				// Convert table to formatted text
-				formattedTable := formatTableForSlack(tableLines)
-				result = append(result, formattedTable)
-				i = j
-				continue
+			warned = warned || len(resp.Warnings) > 0
+			if encErr := encoder.Encode(resp); encErr != nil {
+				return warned, encErr
 			}
 		}
 
-		result = append(result, line)
-		i++
+		if err == io.EOF {
+			return warned, nil
+		}
+		if err != nil {
+			return warned, err
+		}
 	}
+}
 
-	return strings.Join(result, "\n")
+// document is one input document, with where it came from for warnings
+type document struct {
+	name   string // file name or URL, or "stdin"
+	line   int    // line of the input the document starts on
+	text   string
+	format string // format its name or media type suggests, if any
 }
 
-// formatTableForSlack formats a markdown table for Slack display
-func formatTableForSlack(tableLines []string) string {
-	// Remove empty lines and clean up
-	cleanLines := []string{}
-	for _, line := range tableLines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			cleanLines = append(cleanLines, trimmed)
+// variableRegex finds $VAR, ${VAR} and ${VAR:-default} references
+var variableRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandVariables replaces $VAR, ${VAR} and ${VAR:-default} in text with
+// the values lookup finds. Unset variables without a default stay as written,
+// so prices like $5 survive, and are returned by name.
+func expandVariables(text string, lookup func(string) (string, bool)) (string, []string) {
+	var unset []string
+	text = variableRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := variableRegex.FindStringSubmatch(match)
+		name := m[1] + m[3]
+		if value, ok := lookup(name); ok && (value != "" || !strings.Contains(match, ":-")) {
+			return value
 		}
+		if strings.Contains(match, ":-") {
+			return m[2]
+		}
+		if !slices.Contains(unset, name) {
+			unset = append(unset, name)
+		}
+		return match
+	})
+	return text, unset
+}
+
+// splitDocuments splits text at every line consisting of delimiter alone
+func splitDocuments(text string, delimiter string) []string {
+	var documents []string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == delimiter {
+			documents = append(documents, strings.Join(current, "\n"))
+			current = nil
+			continue
+		}
+		current = append(current, line)
 	}
+	return append(documents, strings.Join(current, "\n"))
+}
 
-	if len(cleanLines) < 2 {
-		return strings.Join(tableLines, "\n") // Return as-is if not a proper table
+// writeOutputFile writes converted text to path, exiting on failure
+func writeOutputFile(path string, text string) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error creating output file: %v\n"), err)
+		os.Exit(exitIO)
 	}
+	defer file.Close()
 
-	// Parse table
-	rows := [][]string{}
-	separatorRegex := regexp.MustCompile(`^\|[\s\-\|:]+\|$`)
+	_, err = file.WriteString(text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error writing to file: %v\n"), err)
+		os.Exit(exitIO)
+	}
+	fmt.Printf(tr("Converted text written to %s\n"), path)
+}
 
-	for _, line := range cleanLines {
-		// Skip separator lines (|---|---|)
-		if separatorRegex.MatchString(line) {
-			continue
+// outTemplateRegex finds the {{placeholders}} of an --out-template
+var outTemplateRegex = regexp.MustCompile(`\{\{\s*(\w*)\s*\}\}`)
+
+// checkOutTemplate returns an error naming the first placeholder
+// expandOutTemplate does not know
+func checkOutTemplate(template string) error {
+	for _, m := range outTemplateRegex.FindAllStringSubmatch(template, -1) {
+		switch m[1] {
+		case "dir", "name", "ext", "n":
+		default:
+			return fmt.Errorf("unknown placeholder %s", m[0])
 		}
+	}
+	return nil
+}
 
-		// Split by | and clean up
-		parts := strings.Split(line, "|")
-		if len(parts) >= 3 { // Should have at least |cell1|cell2|
-			cells := []string{}
-			for i := 1; i < len(parts)-1; i++ { // Remove first/last empty
-				cells = append(cells, strings.TrimSpace(parts[i]))
-			}
-			if len(cells) > 0 {
-				rows = append(rows, cells)
-			}
+// expandOutTemplate returns the output path of the n-th document: {{dir}}
+// and {{name}} are the directory and base name of the input without its
+// extension, {{ext}} the extension without the dot and {{n}} the document
+// number. URLs and stdin have the directory ".".
+func expandOutTemplate(template string, doc document, n int) string {
+	dir, base := ".", doc.name
+	if isRemote(doc.name) {
+		if u, err := url.Parse(doc.name); err == nil {
+			base = path.Base(u.Path)
 		}
+	} else if doc.name != "stdin" {
+		dir, base = filepath.Dir(doc.name), filepath.Base(doc.name)
 	}
-
-	if len(rows) == 0 {
-		return strings.Join(tableLines, "\n")
+	ext := filepath.Ext(base)
+	if base == "/" || base == "." || base == ext {
+		base, ext = "index", ""
 	}
 
-	// Calculate column widths
-	maxCols := 0
-	for _, row := range rows {
-		if len(row) > maxCols {
-			maxCols = len(row)
+	expanded := outTemplateRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch outTemplateRegex.FindStringSubmatch(placeholder)[1] {
+		case "dir":
+			return dir
+		case "name":
+			return strings.TrimSuffix(base, ext)
+		case "ext":
+			return strings.TrimPrefix(ext, ".")
+		default:
+			return fmt.Sprint(n)
 		}
+	})
+	return filepath.Clean(expanded)
+}
+
+// expandFooter returns the --footer of the messages converted from the
+// document named name: {author} is the current user, {file} the input's base
+// name and {time} a Slack date showing when in the reader's time zone
+func expandFooter(template string, name string, now time.Time) string {
+	author := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		author = cmp.Or(u.Name, u.Username, author)
 	}
+	file := name
+	if !isRemote(name) && name != "stdin" {
+		file = filepath.Base(name)
+	}
+	date := fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", now.Unix(), now.UTC().Format("2006-01-02 15:04 UTC"))
+	return strings.NewReplacer("{author}", author, "{file}", file, "{time}", date).Replace(template)
+}
 
-	colWidths := make([]int, maxCols)
-	for col := 0; col < maxCols; col++ {
-		maxWidth := 0
-		for _, row := range rows {
-			if col < len(row) && len(row[col]) > maxWidth {
-				maxWidth = len(row[col])
+// markdownExts are the extensions of the files --mirror finds in directories
+var markdownExts = []string{".md", ".markdown"}
+
+// mirrorInputs returns the files --mirror converts: the file arguments and
+// the markdown files in directory arguments, leaving out hidden directories
+// and files whose output at template is newer than they are
+func mirrorInputs(args []string, template string) []string {
+	var files []string
+	for _, arg := range args {
+		if isRemote(arg) {
+			fmt.Fprintf(os.Stderr, tr("Error: --mirror converts local files, not %s\n"), arg)
+			os.Exit(exitUsage)
+		}
+		err := filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case entry.IsDir() && path != arg && strings.HasPrefix(entry.Name(), "."):
+				return filepath.SkipDir
+			case entry.IsDir():
+				return nil
+			case path == arg || slices.Contains(markdownExts, strings.ToLower(filepath.Ext(path))):
+				files = append(files, path)
 			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error: File '%s' not found: %v\n"), arg, err)
+			os.Exit(exitIO)
 		}
-		colWidths[col] = maxWidth
 	}
 
-	// Format as code block for better alignment
-	result := []string{"```"}
-
-	for i, row := range rows {
-		formattedRow := []string{}
-		for j, cell := range row {
-			if j < len(colWidths) {
-				formattedRow = append(formattedRow, fmt.Sprintf("%-*s", colWidths[j], cell))
-			} else {
-				formattedRow = append(formattedRow, cell)
-			}
+	stale := files[:0]
+	for _, file := range files {
+		source, err := os.Stat(file)
+		if err != nil {
+			stale = append(stale, file)
+			continue
 		}
-
-		result = append(result, strings.Join(formattedRow, " | "))
-
-		// Add separator after header
-		if i == 0 {
-			separator := []string{}
-			for _, width := range colWidths {
-				separator = append(separator, strings.Repeat("-", width))
-			}
-			result = append(result, strings.Join(separator, "-|-"))
+		output, err := os.Stat(expandOutTemplate(template, document{name: file}, 1))
+		if err != nil || !output.ModTime().After(source.ModTime()) {
+			stale = append(stale, file)
 		}
 	}
+	if skipped := len(files) - len(stale); skipped > 0 {
+		fmt.Printf(tr("Skipped %d files whose output is up to date\n"), skipped)
+	}
+	return stale
+}
 
-	result = append(result, "```")
-	return strings.Join(result, "\n")
+// checkBlocks exits with the problems when converting the document name to
+// blocks failed
+func checkBlocks(name string, err error) {
+	var blockErrs slackify.BlockErrors
+	if errors.As(err, &blockErrs) {
+		fmt.Fprintf(os.Stderr, tr("Error: %s converts to blocks Slack would reject:\n"), name)
+		for _, blockErr := range blockErrs {
+			fmt.Fprintf(os.Stderr, tr("  %v\n"), blockErr)
+		}
+		os.Exit(exitParse)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error converting %s: %v\n"), name, err)
+		os.Exit(exitParse)
+	}
 }
 
-// markdownToSlack converts markdown text to Slack formatting
-func markdownToSlack(text string) string {
-	// Headers - convert to bold
-	headerRegex1 := regexp.MustCompile(`^### (.*)$`)
-	headerRegex2 := regexp.MustCompile(`^## (.*)$`)
-	headerRegex3 := regexp.MustCompile(`^# (.*)$`)
-	
-	text = headerRegex1.ReplaceAllString(text, "*$1*")
-	text = headerRegex2.ReplaceAllString(text, "*$1*")
-	text = headerRegex3.ReplaceAllString(text, "*$1*")
-
-	// Bold: **text** -> *text*
-	boldRegex := regexp.MustCompile(`\*\*(.*?)\*\*`)
-	text = boldRegex.ReplaceAllString(text, "*$1*")
-
-	// Italic: *text* -> _text_ (but be careful not to affect our new bold)
-	// First pass: temporarily replace bold asterisks
-	boldTempRegex := regexp.MustCompile(`(\*[^*]+\*)`)
-	boldMatches := boldTempRegex.FindAllString(text, -1)
-	for i, match := range boldMatches {
-		placeholder := fmt.Sprintf("BOLD_TEMP%d_TEMP%sTEMP_BOLD", i, match[1:len(match)-1])
-		text = strings.Replace(text, match, placeholder, 1)
-	}
-
-	// Now convert remaining single asterisks to underscores for italic
-	italicRegex := regexp.MustCompile(`\*([^*]+)\*`)
-	text = italicRegex.ReplaceAllString(text, "_$1_")
-
-	// Restore bold
-	boldRestoreRegex := regexp.MustCompile(`BOLD_TEMP\d+_TEMP(.+?)TEMP_BOLD`)
-	text = boldRestoreRegex.ReplaceAllString(text, "*$1*")
-
-	// Code blocks with language - convert to Slack snippets
-	codeBlockRegex := regexp.MustCompile("(?s)```\\w*\\n(.*?)```")
-	text = codeBlockRegex.ReplaceAllString(text, "```$1```")
-
-	// Inline code stays the same: `code`
-
-	// Unordered lists: convert - to •
-	unorderedListRegex := regexp.MustCompile(`(?m)^- `)
-	text = unorderedListRegex.ReplaceAllString(text, "• ")
-	
-	nestedListRegex := regexp.MustCompile(`(?m)^  - `)
-	text = nestedListRegex.ReplaceAllString(text, "  ◦ ")
-
-	// Ordered lists: keep numbers but clean up (no changes needed)
-
-	// Links: [text](url) -> text (url)
-	linkRegex := regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
-	text = linkRegex.ReplaceAllString(text, "$1 ($2)")
-
-	// Blockquotes: > text -> indented text
-	blockquoteRegex := regexp.MustCompile(`(?m)^> `)
-	text = blockquoteRegex.ReplaceAllString(text, "    ")
-
-	// Tables - convert to formatted text blocks
-	text = convertTables(text)
-
-	return text
+// mustEnvelope returns the --format json output for a document, exiting
+// when it cannot be encoded
+func mustEnvelope(markdown, text string, blocks []slackify.Block, warnings []string, meta *slackify.Metadata) string {
+	env, err := jsonEnvelope(markdown, text, blocks, warnings, meta)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error encoding JSON output: %v\n"), err)
+		os.Exit(exitParse)
+	}
+	return env
 }
 
 func main() {
+	if lang := os.Getenv("SLACKIFY_LANG"); lang != "" {
+		if err := setLanguage(lang); err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error: Invalid SLACKIFY_LANG value '%s': %v\n"), lang, err)
+			os.Exit(exitUsage)
+		}
+	}
+	scanLanguage(os.Args[1:])
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "version":
+			runVersion(os.Args[2:])
+			return
+		case "release":
+			runRelease(os.Args[2:])
+			return
+		case "gh-issue":
+			runIssue(os.Args[2:])
+			return
+		case "feed":
+			runFeed(os.Args[2:])
+			return
+		case "lint":
+			runLint(os.Args[2:])
+			return
+		}
+	}
+
 	var outputFile string
-	flag.StringVar(&outputFile, "o", "", "Output file (default: stdout)")
-	flag.StringVar(&outputFile, "output", "", "Output file (default: stdout)")
-	
+	var opts slackify.Options
+	flag.StringVar(&outputFile, "o", "", "Output file, or a template like out-%02d.txt for one file per document or --split message (default: stdout)")
+	flag.StringVar(&outputFile, "output", "", "Output file, or a template like out-%02d.txt for one file per document or --split message (default: stdout)")
+	var preview string
+	flag.StringVar(&preview, "preview", "", "Show mrkdwn output in the terminal instead: ansi approximates its formatting with ANSI styles and marks lines whose words changed from the input")
+	var mirror string
+	flag.StringVar(&mirror, "mirror", "", "Write the output of each input file to DIR/<its path>/<name>.slack.txt, or next to it with ., searching directories for .md files and skipping files whose output is newer")
+	var outTemplate string
+	flag.StringVar(&outTemplate, "out-template", "", "Write one file per document at a path like {{dir}}/{{name}}.slack.txt, using {{dir}}, {{name}}, {{ext}} of the input and the document number {{n}}")
+	var footer string
+	flag.StringVar(&footer, "footer", "", "End each message of split output (--split or richtext) with a subdued footer like \"{author} · {file} · {time}\", using the current user, the input's file name and the time of conversion")
+	conversionFlags(flag.CommandLine, &opts)
+	var timeout time.Duration
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "Time limit for fetching http(s) URL arguments")
+	var encoding string
+	flag.StringVar(&encoding, "encoding", "utf-8", "Input encoding: utf-8, latin1, windows-1252 or auto")
+	var debug bool
+	flag.BoolVar(&debug, "v", false, "Log each conversion stage to stderr")
+	flag.BoolVar(&debug, "debug", false, "Log each conversion stage to stderr")
+	var outputFormat string
+	flag.StringVar(&outputFormat, "format", "mrkdwn", "Output format: mrkdwn text, richtext for a Block Kit payload of rich_text blocks, or json for {\"text\", \"blocks\", \"warnings\", \"stats\"}")
+	var inputFormat string
+	flag.StringVar(&inputFormat, "input-format", "", "Input format: markdown, asciidoc, html, pandoc-json or mrkdwn (default: detect)")
+	var jsonLines bool
+	flag.BoolVar(&jsonLines, "jsonl", false, "Read {\"id\", \"markdown\"} JSON Lines from stdin and write {\"id\", \"mrkdwn\", \"warnings\"} lines")
+	var jsonRPC bool
+	flag.BoolVar(&jsonRPC, "jsonrpc", false, "Stay running and answer JSON-RPC 2.0 requests on stdin, one per line: convert and convertToBlocks with {\"markdown\"}, and shutdown")
+	var delimiter string
+	flag.StringVar(&delimiter, "delimiter", "", "Line separating documents within the input, repeated between the outputs")
+	var changelogSection string
+	flag.StringVar(&changelogSection, "changelog-section", "", "Convert only this release's section of a Keep a Changelog file, or latest")
+	var maxLength int
+	flag.IntVar(&maxLength, "max-length", 0, "Fail without writing output when a converted document is longer than N characters")
+	var fidelity bool
+	flag.BoolVar(&fidelity, "fidelity", false, "Print to stderr how faithfully each document converts: the percentage of its blocks and inline nodes that lose nothing")
+	var stats bool
+	flag.BoolVar(&stats, "stats", false, "Print character, word, block and message counts to stderr")
+	var separator string
+	flag.StringVar(&separator, "separator", "\n\n---\n\n", "Text placed between converted files and --split messages (\\n is a newline)")
+	var split bool
+	flag.BoolVar(&split, "split", false, "Split long mrkdwn output into messages of up to 4000 characters, placing --separator between them")
+	var failOnSecrets bool
+	flag.BoolVar(&failOnSecrets, "fail-on-secrets", false, "Fail without writing output when the input contains tokens, keys or other credentials")
+	var failOnWarning bool
+	flag.BoolVar(&failOnWarning, "fail-on-warning", false, "Fail without writing output when converting prints any warning")
+	var embedSource string
+	flag.StringVar(&embedSource, "embed-source", "", "Keep the source document with the output for later edits: hash (its path and SHA-256) or full (its text too), as message metadata or, for mrkdwn, a FILE.source.json next to the output")
+	var expandEnv bool
+	flag.BoolVar(&expandEnv, "expand-env", false, "Replace $VAR, ${VAR} and ${VAR:-default} with environment variables before converting")
+
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] [file]\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Convert Markdown to Slack formatting\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
+		fmt.Fprintf(os.Stderr, tr("Usage: %s [options] [file...]\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("       %s release [options] owner/repo [--tag TAG]\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("       %s gh-issue [options] owner/repo#123 [--comments]\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("       %s feed [options] URL [--state FILE]\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("       %s lint [--target slack] [file...]\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("       %s version [--check-update]\n\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("Convert Markdown to Slack formatting\n\n"))
+		fmt.Fprintf(os.Stderr, tr("Options:\n"))
+		translateUsage(flag.CommandLine)
 		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  %s file.md\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  echo \"**bold text**\" | %s\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s < input.md > output.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("\nExamples:\n"))
+		fmt.Fprintf(os.Stderr, tr("  %s file.md\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("  %s https://example.com/README.md\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("  %s --separator '\\n\\n' a.md b.md\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("  echo \"**bold text**\" | %s\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("  %s < input.md > output.txt\n"), os.Args[0])
+		fmt.Fprintf(os.Stderr, tr("\nExit status:\n"))
+		fmt.Fprintf(os.Stderr, tr("  0  success\n"))
+		fmt.Fprintf(os.Stderr, tr("  %d  invalid flags, arguments or missing input\n"), exitUsage)
+		fmt.Fprintf(os.Stderr, tr("  %d  reading input or writing output failed\n"), exitIO)
+		fmt.Fprintf(os.Stderr, tr("  %d  the input could not be parsed or converted\n"), exitParse)
+		fmt.Fprintf(os.Stderr, tr("  %d  the output is longer than --max-length\n"), exitTooLong)
+		fmt.Fprintf(os.Stderr, tr("  %d  a Slack API request failed\n"), exitSlack)
+		fmt.Fprintf(os.Stderr, tr("  %d  the input contains credentials and --fail-on-secrets is set\n"), exitSecrets)
+		fmt.Fprintf(os.Stderr, tr("  %d  converting printed warnings and --fail-on-warning is set\n"), exitWarning)
+		fmt.Fprintf(os.Stderr, tr("  %d  lint found problems in the input\n"), exitLint)
 	}
-	
+
 	flag.Parse()
 
-	var reader io.Reader
-	var inputFile string
+	applyPreset(flag.CommandLine)
+	checkOptions(opts)
+	applyUsergroups(flag.CommandLine, &opts)
+
+	if strings.Contains(outputFile, "%") && strings.Contains(fmt.Sprintf(outputFile, 1), "%!") {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid output template '%s' (use a single number verb like %%02d)\n"), outputFile)
+		os.Exit(exitUsage)
+	}
+
+	if outTemplate != "" && outputFile != "" {
+		fmt.Fprintf(os.Stderr, tr("Error: Use either --output or --out-template, not both\n"))
+		os.Exit(exitUsage)
+	}
+	if preview != "" && preview != "ansi" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --preview value '%s' (use ansi)\n"), preview)
+		os.Exit(exitUsage)
+	}
+	if preview != "" && (outputFormat != "mrkdwn" || outputFile != "" || outTemplate != "" || mirror != "") {
+		fmt.Fprintf(os.Stderr, tr("Error: --preview shows mrkdwn in the terminal; use it without --format, --output, --out-template or --mirror\n"))
+		os.Exit(exitUsage)
+	}
+
+	if mirror != "" {
+		if outTemplate != "" || outputFile != "" {
+			fmt.Fprintf(os.Stderr, tr("Error: Use --mirror without --output or --out-template\n"))
+			os.Exit(exitUsage)
+		}
+		if flag.NArg() == 0 {
+			fmt.Fprintf(os.Stderr, tr("Error: --mirror needs files or directories to convert\n"))
+			os.Exit(exitUsage)
+		}
+		outTemplate = filepath.Join(mirror, "{{dir}}", "{{name}}.slack.txt")
+	}
+	if err := checkOutTemplate(outTemplate); err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --out-template '%s': %v (use {{dir}}, {{name}}, {{ext}} or {{n}})\n"), outTemplate, err)
+		os.Exit(exitUsage)
+	}
+
+	if debug {
+		opts.Trace = os.Stderr
+	}
+
+	switch inputFormat {
+	case "", "markdown", "asciidoc", "html", "pandoc-json", "mrkdwn":
+	default:
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --input-format value '%s' (use markdown, asciidoc, html, pandoc-json or mrkdwn)\n"), inputFormat)
+		os.Exit(exitUsage)
+	}
+
+	if outputFormat != "mrkdwn" && outputFormat != "richtext" && outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --format value '%s' (use mrkdwn, richtext or json)\n"), outputFormat)
+		os.Exit(exitUsage)
+	}
 
-	// Determine input source
-	if flag.NArg() > 0 {
-		inputFile = flag.Arg(0)
-		file, err := os.Open(inputFile)
+	if embedSource != "" && embedSource != "hash" && embedSource != "full" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --embed-source value '%s' (use hash or full)\n"), embedSource)
+		os.Exit(exitUsage)
+	}
+	if embedSource != "" && outputFormat == "mrkdwn" && outputFile == "" && outTemplate == "" {
+		fmt.Fprintf(os.Stderr, tr("Error: --embed-source with mrkdwn output needs --output or --out-template to write the source file next to\n"))
+		os.Exit(exitUsage)
+	}
+
+	if encoding != "utf-8" && encoding != "latin1" && encoding != "windows-1252" && encoding != "auto" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --encoding value '%s' (use utf-8, latin1, windows-1252 or auto)\n"), encoding)
+		os.Exit(exitUsage)
+	}
+
+	converter := slackify.New(slackify.WithOptions(opts))
+
+	if jsonLines {
+		warned, err := convertJSONLines(os.Stdin, os.Stdout, converter)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: File '%s' not found: %v\n", inputFile, err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, tr("Error processing JSON Lines: %v\n"), err)
+			os.Exit(exitIO)
+		}
+		// Responses are written as they come, so only the exit status can tell
+		if failOnWarning && warned {
+			os.Exit(exitWarning)
+		}
+		return
+	}
+
+	if jsonRPC {
+		if err := serveJSONRPC(os.Stdin, os.Stdout, converter); err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error processing JSON-RPC requests: %v\n"), err)
+			os.Exit(exitIO)
+		}
+		return
+	}
+
+	var documents []document
+
+	inputs := flag.Args()
+	if mirror != "" {
+		inputs = mirrorInputs(inputs, outTemplate)
+		if len(inputs) == 0 {
+			return
+		}
+	}
+
+	// Determine input source: every file argument in turn, or stdin
+	if len(inputs) > 0 {
+		for _, inputFile := range inputs {
+			if isRemote(inputFile) {
+				markdownText, format, err := fetchInput(inputFile, encoding, timeout)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, tr("Error fetching '%s': %v\n"), inputFile, err)
+					os.Exit(exitIO)
+				}
+				documents = append(documents, document{inputFile, 1, markdownText, format})
+				continue
+			}
+
+			file, err := os.Open(inputFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, tr("Error: File '%s' not found: %v\n"), inputFile, err)
+				os.Exit(exitIO)
+			}
+			markdownText, err := readInput(file, encoding)
+			file.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, tr("Error reading '%s': %v\n"), inputFile, err)
+				os.Exit(exitIO)
+			}
+			documents = append(documents, document{inputFile, 1, markdownText, formatFromName(inputFile)})
 		}
-		defer file.Close()
-		reader = file
 	} else {
 		// Check if stdin has data
 		stat, err := os.Stdin.Stat()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error checking stdin: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, tr("Error checking stdin: %v\n"), err)
+			os.Exit(exitIO)
 		}
-		
+
 		if (stat.Mode() & os.ModeCharDevice) != 0 {
-			fmt.Fprintf(os.Stderr, "Error: No input provided. Use a file argument or pipe input.\n")
-			fmt.Fprintf(os.Stderr, "Try: %s --help\n", os.Args[0])
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, tr("Error: No input provided. Use a file argument or pipe input.\n"))
+			fmt.Fprintf(os.Stderr, tr("Try: %s --help\n"), os.Args[0])
+			os.Exit(exitUsage)
 		}
-		reader = os.Stdin
-	}
 
-	// Read input
-	scanner := bufio.NewScanner(reader)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+		markdownText, err := readInput(os.Stdin, encoding)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error reading input: %v\n"), err)
+			os.Exit(exitIO)
+		}
+		documents = append(documents, document{"stdin", 1, markdownText, ""})
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-		os.Exit(1)
+	// A delimited stream holds several documents, and the output keeps them apart the same way
+	separator = strings.ReplaceAll(separator, "\\n", "\n")
+	if delimiter != "" {
+		var split []document
+		for _, doc := range documents {
+			line := doc.line
+			for _, text := range splitDocuments(doc.text, delimiter) {
+				split = append(split, document{doc.name, line, text, doc.format})
+				line += strings.Count(text, "\n") + 2
+			}
+		}
+		documents = split
+		separator = "\n" + delimiter + "\n"
 	}
+	if outputFormat == "richtext" || outputFormat == "json" {
+		// A stream of JSON payloads, one per message or document
+		separator = "\n"
+	}
+
+	// Convert, going through markdown for other input formats
+	now := time.Now()
+	converted := make([]string, len(documents))
+	sources := make([]*slackify.Metadata, len(documents))
+	previews := make([]string, len(documents))
+	// The messages of each document --split divides, for numbered -o files
+	messages := make([][]string, len(documents))
+	warned := false
+	for i, doc := range documents {
+		// The document as written, before variables are filled in
+		original := doc.text
+		if expandEnv {
+			var unset []string
+			doc.text, unset = expandVariables(doc.text, os.LookupEnv)
+			for _, name := range unset {
+				fmt.Fprintf(os.Stderr, tr("Warning: %s: environment variable %s is not set and stays as written\n"), doc.name, name)
+				warned = true
+			}
+			documents[i] = doc
+		}
+
+		// Warnings about the document, which --format json carries along
+		var warnings []string
+		secrets := converter.Secrets(doc.text)
+		for _, w := range secrets {
+			warnings = append(warnings, fmt.Sprintf("%d:%d: %s", doc.line+w.Line-1, w.Column, w.Message))
+			if failOnSecrets {
+				fmt.Fprintf(os.Stderr, tr("Error: %s:%d:%d: %s\n"), doc.name, doc.line+w.Line-1, w.Column, w.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, tr("Warning: %s:%d:%d: %s\n"), doc.name, doc.line+w.Line-1, w.Column, w.Message)
+				warned = true
+			}
+		}
+		if failOnSecrets && len(secrets) > 0 {
+			os.Exit(exitSecrets)
+		}
 
-	markdownText := strings.Join(lines, "\n")
+		format := inputFormat
+		if format == "" {
+			format = doc.format
+		}
+		if format == "" {
+			var ambiguous bool
+			format, ambiguous = slackify.DetectFormat(doc.text)
+			if ambiguous {
+				fmt.Fprintf(os.Stderr, tr("Warning: Input looks like %s but could be another format; use --input-format to choose\n"), format)
+				warned = true
+			}
+		}
 
-	// Convert
-	slackText := markdownToSlack(markdownText)
+		if embedSource != "" {
+			if opts.Redact {
+				original = slackify.Redact(original, opts)
+			}
+			path := doc.name
+			if path == "stdin" {
+				path = ""
+			}
+			meta := slackify.SourceMetadata(original, path, format, embedSource == "full")
+			sources[i] = &meta
+		}
 
-	// Output
-	if outputFile != "" {
-		file, err := os.Create(outputFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
-			os.Exit(1)
+		// Text that already is Slack mrkdwn must not be converted again
+		if format == "mrkdwn" {
+			converted[i] = doc.text
+			if opts.Redact {
+				converted[i] = slackify.Redact(doc.text, opts)
+			}
+			if outputFormat == "json" {
+				converted[i] = mustEnvelope(doc.text, converted[i], nil, warnings, sources[i])
+			}
+			if preview != "" {
+				previews[i] = previewANSI(doc.text, converted[i])
+			}
+			continue
 		}
-		defer file.Close()
 
-		_, err = file.WriteString(slackText)
+		markdownText, err := slackify.ToMarkdown(doc.text, format)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing to file: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, tr("Error parsing %s input in %s: %v\n"), format, doc.name, err)
+			os.Exit(exitParse)
 		}
-		fmt.Printf("Converted text written to %s\n", outputFile)
-	} else {
+
+		if changelogSection != "" {
+			markdownText, err = slackify.ChangelogSection(markdownText, changelogSection)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, tr("Error: %v in %s\n"), err, doc.name)
+				os.Exit(exitParse)
+			}
+		}
+
+		if fidelity {
+			f := converter.Fidelity(markdownText)
+			fmt.Fprintf(os.Stderr, tr("Fidelity: %s: %.1f%% (%d of %d nodes degraded)\n"), doc.name, f.Score(), f.Degraded, f.Nodes)
+		}
+
+		// Positions only make sense in the markdown the user wrote, and the
+		// checks describe what mrkdwn output loses
+		if format == "markdown" && changelogSection == "" && outputFormat != "richtext" {
+			for _, w := range converter.Check(markdownText) {
+				warnings = append(warnings, fmt.Sprintf("%d:%d: %s", doc.line+w.Line-1, w.Column, w.Message))
+				fmt.Fprintf(os.Stderr, tr("Warning: %s:%d:%d: %s\n"), doc.name, doc.line+w.Line-1, w.Column, w.Message)
+				warned = true
+			}
+		}
+		// The footer names the document, so each gets a converter of its own
+		messageConverter := converter
+		if footer != "" {
+			messageConverter = slackify.New(slackify.WithOptions(opts), slackify.WithFooter(expandFooter(footer, doc.name, now)))
+		}
+		if outputFormat == "richtext" {
+			payloads, err := messageConverter.ConvertRichText(markdownText)
+			checkBlocks(doc.name, err)
+			if sources[i] != nil {
+				for j := range payloads {
+					if payloads[j], err = withMetadata(payloads[j], *sources[i]); err != nil {
+						fmt.Fprintf(os.Stderr, tr("Error encoding JSON output: %v\n"), err)
+						os.Exit(exitParse)
+					}
+				}
+			}
+			converted[i] = strings.Join(payloads, "\n")
+			continue
+		}
+		if outputFormat == "json" {
+			blocks, err := converter.ConvertToBlocks(markdownText)
+			checkBlocks(doc.name, err)
+			converted[i] = mustEnvelope(markdownText, converter.Convert(markdownText), blocks, warnings, sources[i])
+			continue
+		}
+		if split {
+			messages[i] = messageConverter.ConvertMessages(markdownText)
+			converted[i] = strings.Join(messages[i], separator)
+		} else {
+			converted[i] = converter.Convert(markdownText)
+		}
+		if preview != "" {
+			previews[i] = previewANSI(markdownText, converted[i])
+		}
+	}
+	slackText := strings.Join(converted, separator)
+
+	if stats {
+		markdown := make([]string, len(documents))
+		for i, doc := range documents {
+			markdown[i] = doc.text
+		}
+		writeStats(os.Stderr, markdown, converted)
+	}
+
+	if failOnWarning && warned {
+		fmt.Fprintf(os.Stderr, tr("Error: Conversion printed warnings and --fail-on-warning is set\n"))
+		os.Exit(exitWarning)
+	}
+
+	if maxLength > 0 {
+		for i, text := range converted {
+			if n := utf8.RuneCountInString(text); n > maxLength {
+				fmt.Fprintf(os.Stderr, tr("Error: Output for %s is %d characters, over --max-length %d\n"), documents[i].name, n, maxLength)
+				os.Exit(exitTooLong)
+			}
+		}
+	}
+
+	// Output, one file per document when the name is a template
+	switch {
+	case preview != "":
+		fmt.Print(strings.Join(previews, separator))
+	case outTemplate != "":
+		paths := make([]string, len(converted))
+		for i := range converted {
+			paths[i] = expandOutTemplate(outTemplate, documents[i], i+1)
+			if j := slices.Index(paths[:i], paths[i]); j >= 0 {
+				fmt.Fprintf(os.Stderr, tr("Error: --out-template writes both %s and %s to %s (add {{n}} to tell them apart)\n"), documents[j].name, documents[i].name, paths[i])
+				os.Exit(exitUsage)
+			}
+		}
+		for i, text := range converted {
+			if err := os.MkdirAll(filepath.Dir(paths[i]), 0o755); err != nil {
+				fmt.Fprintf(os.Stderr, tr("Error creating output directory: %v\n"), err)
+				os.Exit(exitIO)
+			}
+			writeOutputFile(paths[i], text)
+			if embedSource != "" && outputFormat == "mrkdwn" {
+				writeSourceFile(paths[i], sources[i:i+1])
+			}
+		}
+	case strings.Contains(outputFile, "%"):
+		// One file per --split message, numbered across documents
+		n := 0
+		for i, text := range converted {
+			parts := messages[i]
+			if parts == nil {
+				parts = []string{text}
+			}
+			for _, part := range parts {
+				n++
+				writeOutputFile(fmt.Sprintf(outputFile, n), part)
+				if embedSource != "" && outputFormat == "mrkdwn" {
+					writeSourceFile(fmt.Sprintf(outputFile, n), sources[i:i+1])
+				}
+			}
+		}
+	case outputFile != "":
+		writeOutputFile(outputFile, slackText)
+		if embedSource != "" && outputFormat == "mrkdwn" {
+			writeSourceFile(outputFile, sources)
+		}
+	default:
 		fmt.Print(slackText)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+func TestExpandFooter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		template, name, want string
+	}{
+		{"from {file}", "docs/notes.md", "from notes.md"},
+		{"from {file}", "stdin", "from stdin"},
+		{"from {file}", "https://example.com/a/notes.md", "from https://example.com/a/notes.md"},
+		{"{time}", "notes.md", "<!date^1709285400^{date_short_pretty} {time}|2024-03-01 09:30 UTC>"},
+		{"no placeholders", "notes.md", "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := expandFooter(tt.template, tt.name, now); got != tt.want {
+			t.Errorf("expandFooter(%q, %q) = %q, want %q", tt.template, tt.name, got, tt.want)
+		}
+	}
+
+	if got := expandFooter("by {author}", "notes.md", now); got == "by " || strings.Contains(got, "{author}") {
+		t.Errorf("expandFooter did not fill in {author}: %q", got)
+	}
+}
+
+func TestConvertJSONLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		want   string
+		warned bool
+	}{
+		{"one line", `{"id": 1, "markdown": "**hi**"}` + "\n", `{"id":1,"mrkdwn":"*hi*","warnings":[]}` + "\n", false},
+		{"no final newline", `{"id": "a", "markdown": "hi"}`, `{"id":"a","mrkdwn":"hi","warnings":[]}` + "\n", false},
+		{"crlf and blank lines", "\r\n" + `{"id": 1, "markdown": "a"}` + "\r\n\n  \n" + `{"id": 2, "markdown": "b"}` + "\r\n",
+			`{"id":1,"mrkdwn":"a","warnings":[]}` + "\n" + `{"id":2,"mrkdwn":"b","warnings":[]}` + "\n", false},
+		{"no id", `{"markdown": "a < b"}` + "\n", `{"id":null,"mrkdwn":"a < b","warnings":[]}` + "\n", false},
+		{"object id", `{"id": {"n": 1}, "markdown": "a"}` + "\n", `{"id":{"n":1},"mrkdwn":"a","warnings":[]}` + "\n", false},
+		{"missing markdown", `{"id": 3}` + "\n", `{"id":3,"mrkdwn":"","warnings":["missing \"markdown\" field"]}` + "\n", true},
+		{"invalid json keeps going", "{nope\n" + `{"id": 4, "markdown": "ok"}` + "\n",
+			`{"id":null,"mrkdwn":"","warnings":["invalid JSON: invalid character 'n' looking for beginning of object key string"]}` + "\n" +
+				`{"id":4,"mrkdwn":"ok","warnings":[]}` + "\n", true},
+	}
+	converter := slackify.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			warned, err := convertJSONLines(strings.NewReader(tt.in), &out, converter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want || warned != tt.warned {
+				t.Errorf("convertJSONLines(%q) = %q, %v, want %q, %v", tt.in, out.String(), warned, tt.want, tt.warned)
+			}
+		})
+	}
+}
+
+func TestExpandVariables(t *testing.T) {
+	env := map[string]string{"NAME": "world", "EMPTY": "", "VERSION": "1.2"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	tests := []struct {
+		in, want string
+		unset    []string
+	}{
+		{"Hello $NAME", "Hello world", nil},
+		{"Hello ${NAME}!", "Hello world!", nil},
+		{"v${VERSION}.0", "v1.2.0", nil},
+		{"${MISSING:-fallback}", "fallback", nil},
+		{"${EMPTY:-fallback}", "fallback", nil},
+		{"[$EMPTY]", "[]", nil},
+		{"${NAME:-fallback}", "world", nil},
+		{"Costs $5", "Costs $5", nil},
+		{"$MISSING and ${MISSING} and $OTHER", "$MISSING and ${MISSING} and $OTHER", []string{"MISSING", "OTHER"}},
+	}
+	for _, tt := range tests {
+		got, unset := expandVariables(tt.in, lookup)
+		if got != tt.want || !slices.Equal(unset, tt.unset) {
+			t.Errorf("expandVariables(%q) = %q, %v, want %q, %v", tt.in, got, unset, tt.want, tt.unset)
+		}
+	}
+}
+
+func TestExpandOutTemplate(t *testing.T) {
+	tests := []struct {
+		template, name string
+		n              int
+		want           string
+	}{
+		{"{{dir}}/{{name}}.slack.txt", "docs/guide.md", 1, "docs/guide.slack.txt"},
+		{"out/{{name}}.{{ext}}.txt", "docs/guide.md", 1, "out/guide.md.txt"},
+		{"out/{{ name }}-{{n}}.txt", "README", 3, "out/README-3.txt"},
+		{"{{dir}}/{{name}}.txt", "stdin", 2, "stdin.txt"},
+		{"{{dir}}/{{name}}.txt", "https://example.com/docs/page.html", 1, "page.txt"},
+		{"{{dir}}/{{name}}.txt", "https://example.com/", 1, "index.txt"},
+		{"{{dir}}/{{name}}.txt", "docs/.hidden", 1, "docs/index.txt"},
+		{"out/../{{dir}}/{{name}}.txt", "a/b.md", 1, "a/b.txt"},
+	}
+	for _, tt := range tests {
+		if got := expandOutTemplate(tt.template, document{name: tt.name}, tt.n); got != filepath.FromSlash(tt.want) {
+			t.Errorf("expandOutTemplate(%q, %q, %d) = %q, want %q", tt.template, tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOutTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		ok       bool
+	}{
+		{"{{dir}}/{{name}}.{{ext}}-{{n}}.txt", true},
+		{"plain.txt", true},
+		{"{{ name }}.txt", true},
+		{"{{base}}.txt", false},
+		{"{{}}.txt", false},
+	}
+	for _, tt := range tests {
+		if err := checkOutTemplate(tt.template); (err == nil) != tt.ok {
+			t.Errorf("checkOutTemplate(%q) = %v, want ok %v", tt.template, err, tt.ok)
+		}
+	}
+}
+
+func TestMirrorInputs(t *testing.T) {
+	root := t.TempDir()
+	docs := filepath.Join(root, "docs")
+	write := func(path string, modTime time.Time) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# Doc\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old, recent := time.Now().Add(-time.Hour), time.Now()
+	for _, name := range []string{"a.md", "b.markdown", "sub/c.MD", "notes.txt", ".git/d.md", "current.md"} {
+		write(filepath.Join(docs, name), old)
+	}
+	extra := filepath.Join(root, "extra.txt")
+	write(extra, old)
+
+	template := filepath.Join(root, "out", "{{dir}}", "{{name}}.slack.txt")
+	output := func(file string) string {
+		return expandOutTemplate(template, document{name: file}, 1)
+	}
+	// Up to date, stale and missing outputs
+	write(output(filepath.Join(docs, "current.md")), recent)
+	write(output(filepath.Join(docs, "a.md")), old.Add(-time.Minute))
+
+	got := mirrorInputs([]string{docs, extra}, template)
+	want := []string{
+		filepath.Join(docs, "a.md"),
+		filepath.Join(docs, "b.markdown"),
+		filepath.Join(docs, "sub", "c.MD"),
+		extra,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("mirrorInputs = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeInput(t *testing.T) {
+	tests := []struct {
+		data, encoding, want string
+	}{
+		{"caf\xe9", "latin1", "café"},
+		{"\x80 \x85 \x93quoted\x94 \x96 \x99", "windows-1252", "€ … “quoted” – ™"},
+		{"\x81\x8d\x8f\x90\x9d", "windows-1252", "\u0081\u008d\u008f\u0090\u009d"},
+		{"\xe9\xff", "windows-1252", "éÿ"},
+		{"\x80", "latin1", "\u0080"},
+		{"café", "auto", "café"},
+		{"\xef\xbb\xbfcafé", "auto", "\ufeffcafé"},
+		{"caf\xe9", "auto", "café"},
+		{"café", "utf-8", "café"},
+	}
+	for _, tt := range tests {
+		got, err := decodeInput([]byte(tt.data), tt.encoding)
+		if err != nil || got != tt.want {
+			t.Errorf("decodeInput(%q, %s) = %q, %v, want %q", tt.data, tt.encoding, got, err, tt.want)
+		}
+	}
+	if _, err := decodeInput([]byte("x"), "utf-16"); err == nil {
+		t.Error("decodeInput accepted utf-16")
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// conversionFlags registers the flags for the conversion options on flags,
+// so subcommands convert the same way the main command does
+func conversionFlags(flags *flag.FlagSet, opts *slackify.Options) {
+	flags.String("lang", "", "Language of messages, like de, or a JSON file mapping English messages to translations (default: $SLACKIFY_LANG or English)")
+	flags.String("preset", "", "Start from a bundle of options: github (slack links, emoji bullets), minimal (ASCII, no emoji) or blockkit (rich_text blocks); flags given explicitly win")
+	flags.StringVar(&opts.Punctuation, "punctuation", "", "Normalize quotes, dashes and ellipses: ascii or unicode")
+	flags.BoolVar(&opts.PreserveSpacing, "preserve-spacing", false, "Keep blank lines and trailing whitespace exactly as written")
+	flags.BoolVar(&opts.Compact, "compact", false, "Collapse runs of blank lines and trim trailing whitespace")
+	flags.BoolVar(&opts.Reflow, "reflow", false, "Join hard-wrapped paragraph lines into single lines")
+	flags.IntVar(&opts.TruncateAt, "truncate-at", 0, "Truncate output to N characters at a block boundary")
+	flags.StringVar(&opts.MoreURL, "more-url", "", "Link to the full document appended to truncated output")
+	flags.BoolVar(&opts.Index, "index", false, "Lead output split into several messages (--split or richtext) with one listing the section titles and the part each starts in")
+	flags.BoolVar(&opts.PartNumbers, "part-numbers", false, "Start each message of split output (--split or richtext) with a subdued part 2/5 label")
+	flags.IntVar(&opts.MaxMessages, "max-messages", 0, "Keep split output (--split or richtext) to N messages, noting how many more there were")
+	flags.StringVar(&opts.Spoilers, "spoilers", "", "Render ||spoilers|| and <details> blocks as quote, plain or hide (default: as written)")
+	flags.StringVar(&opts.SpoilerLabel, "spoiler-label", "", "Label shown for spoilers (default \":no_entry_sign: *Spoiler*\")")
+	flags.StringVar(&opts.InlineFootnotes, "inline-footnotes", "notes", "Render ^[inline footnotes] as numbered notes at the end or as (parenthetical) text: notes or parens")
+	flags.StringVar(&opts.Highlight, "highlight", "bold", "Render ==highlighted== text bold, plain, or wrapped in an emoji code like :large_yellow_circle:")
+	flags.StringVar(&opts.WikiURL, "wiki-url", "", "URL template for [[wiki links]] using {page} or {slug} (default: plain text)")
+	flags.StringVar(&opts.Dialect, "dialect", "", "Normalize quirks of a markdown flavor first: notion")
+	flags.BoolVar(&opts.CommonMarkBlocks, "commonmark-blocks", false, "Rewrite the CommonMark block constructs the default parser misses: setext headings, indented code, one-line link reference definitions, * and + bullets, nested quotes and lazy continuation lines")
+	flags.StringVar(&opts.Critic, "critic", "show", "Resolve CriticMarkup edits: accept, reject or show")
+	flags.StringVar(&opts.Abbreviations, "abbreviations", "inline", "Place *[ABBR]: definitions inline (first use) or in a glossary")
+	flags.StringVar(&opts.LinkStyle, "links", "inline", "Render links inline as text (url), as slack <url|text> links, as text only or as footnotes: text [1] with the URLs listed at the end")
+	flags.StringVar(&opts.TableMode, "tables", "code", "Render tables as a code block, plain aligned text or raw markdown")
+	flags.BoolVar(&opts.TableIsolates, "table-isolates", false, "Wrap right-to-left table cells in Unicode directional isolates")
+	flags.Func("table-columns", "Comma-separated table columns to show, in order, by header or number; -name leaves one out", func(value string) error {
+		opts.TableColumns = strings.Split(value, ",")
+		return nil
+	})
+	flags.Func("gfm", "Comma-separated GFM extensions to convert: tables, strikethrough, tasklists, autolinks, footnotes; -name turns one off (default: all)", func(value string) error {
+		opts.GFM = strings.Split(value, ",")
+		for _, extension := range opts.GFM {
+			if name := strings.TrimPrefix(strings.TrimSpace(extension), "-"); !slices.Contains(slackify.GFMExtensions, name) {
+				return fmt.Errorf("unknown extension %q (use %s)", name, strings.Join(slackify.GFMExtensions, ", "))
+			}
+		}
+		return nil
+	})
+	flags.StringVar(&opts.TableSort, "table-sort", "", "Sort table rows by a column, by header or number; -column sorts descending")
+	flags.IntVar(&opts.TableLimit, "table-limit", 0, "Keep the first N rows of each table, noting how many more there were")
+	flags.IntVar(&opts.MaxCellWidth, "max-cell-width", 0, "Cut table cells wider than N columns short with an ellipsis")
+	flags.BoolVar(&opts.CellFootnotes, "cell-footnotes", false, "List the full values of cells cut by --max-cell-width under the table")
+	flags.StringVar(&opts.HeadingStyle, "heading-style", "bold", "Render headings bold, plain, caps (upper-case levels 1 and 2) or unicode (underline level 1), or a style per level like caps,bold,plain")
+	flags.BoolVar(&opts.Escape, "escape", false, "Escape &, < and > as Slack requires")
+	flags.BoolVar(&opts.ASCII, "ascii", false, "Keep output to plain ASCII for screen readers and limited terminals: - bullets, no inserted emoji or decorative Unicode")
+	flags.BoolVar(&opts.NoEmoji, "no-emoji", false, "Remove Unicode emoji and :shortcode: emoji outside code, and insert none")
+	flags.Func("bullets", "Comma-separated list markers, one per nesting level (default \"•,◦\")", func(value string) error {
+		opts.Bullets = strings.Split(value, ",")
+		for _, bullet := range opts.Bullets {
+			if strings.TrimSpace(bullet) == "" {
+				return fmt.Errorf("empty marker in %q", value)
+			}
+		}
+		return nil
+	})
+	flags.StringVar(&opts.OrderedLists, "ordered-lists", "number", "Render ordered list markers as number (1.), paren (1)), bold or bullets")
+	flags.IntVar(&opts.Indent, "indent", 2, "Spaces per list nesting level")
+	flags.Func("glossary", "YAML file of \"term: replacement\" lines applied after conversion, outside code", func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		glossary, err := parseGlossary(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		opts.Glossary = glossary
+		return nil
+	})
+	flags.String("usergroups", "", "Turn @handles of Slack user groups into group mentions, with handles and IDs from a file of \"handle: S123\" lines, or api to fetch them with usergroups.list and $SLACK_TOKEN")
+	flags.Func("block-hosts", "Comma-separated hosts, like jira.corp.example or *.internal, whose links are removed", func(value string) error {
+		opts.BlockedHosts = append(opts.BlockedHosts, strings.Split(value, ",")...)
+		return nil
+	})
+	flags.Func("allow-hosts", "Comma-separated hosts links may point at; links to others are removed", func(value string) error {
+		opts.AllowedHosts = append(opts.AllowedHosts, strings.Split(value, ",")...)
+		return nil
+	})
+	flags.Func("ticket-link", "Link ticket references, as PATTERN=URL like 'JIRA-\\d+=https://jira.example.com/browse/$0' ($0 is the reference, $1 on its submatches); repeatable", func(value string) error {
+		pattern, template, ok := strings.Cut(value, "=")
+		if !ok || pattern == "" || template == "" {
+			return fmt.Errorf("%q is not PATTERN=URL", value)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+		opts.TicketLinks = append(opts.TicketLinks, slackify.TicketLink{Pattern: pattern, URL: template})
+		return nil
+	})
+	flags.StringVar(&opts.CommitURL, "commit-url", "", "Link commit SHAs, shown by their first 7 characters, to a URL template like https://github.com/owner/repo/commit/{sha}")
+	flags.StringVar(&opts.InternalLinks, "internal-links", "text", "Render removed links as text alone or text with a marker")
+	flags.BoolVar(&opts.CleanURLs, "clean-urls", false, "Remove tracking parameters like utm_source, gclid and fbclid from URLs")
+	flags.Func("shorten-with", "Shorten long URLs with a service, like https://sho.rt/api?url={url}, which returns the short URL", func(template string) error {
+		shortener, err := newHTTPShortener(template, 10*time.Second)
+		if err != nil {
+			return err
+		}
+		opts.Shortener = shortener
+		return nil
+	})
+	flags.IntVar(&opts.ShortenOver, "shorten-over", 60, "Shorten only URLs longer than N characters")
+	flags.BoolVar(&opts.Redact, "redact", false, "Mask tokens, keys and other credentials as [REDACTED]")
+	flags.Func("secret-patterns", "File of extra credential patterns for --redact, one regular expression per line", func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if _, err := regexp.Compile(line); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			opts.SecretPatterns = append(opts.SecretPatterns, line)
+		}
+		return nil
+	})
+	flags.BoolVar(&opts.CodeFallback, "code-fallback", false, "Show raw HTML and deeply nested lists or quotes verbatim in code blocks")
+}
+
+// presets are the flag values each --preset sets
+var presets = map[string]map[string]string{
+	"github": {
+		"links":   "slack",
+		"bullets": ":small_blue_diamond:,:small_orange_diamond:",
+		"tables":  "code",
+	},
+	"minimal": {
+		"ascii":         "true",
+		"heading-style": "plain",
+		"links":         "inline",
+		"compact":       "true",
+	},
+	"blockkit": {
+		"format": "richtext",
+		"links":  "slack",
+	},
+}
+
+// presetConflicts maps preset flags to the flag that overrides them when
+// given on the command line, since checkOptions rejects the two together
+var presetConflicts = map[string]string{
+	"compact": "preserve-spacing",
+}
+
+// applyPreset sets the flags of the chosen --preset that were not given on
+// the command line. Flags the flag set lacks, like --format in subcommands,
+// and flags conflicting with one that was given are skipped.
+func applyPreset(flags *flag.FlagSet) {
+	name := flags.Lookup("preset").Value.String()
+	if name == "" {
+		return
+	}
+	preset, ok := presets[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --preset value '%s' (use github, minimal or blockkit)\n"), name)
+		os.Exit(exitUsage)
+	}
+
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for flagName, value := range preset {
+		if given[flagName] || given[presetConflicts[flagName]] || flags.Lookup(flagName) == nil {
+			continue
+		}
+		if err := flags.Set(flagName, value); err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error: --preset %s: %v\n"), name, err)
+			os.Exit(exitUsage)
+		}
+	}
+}
+
+// applyUsergroups loads the user groups named by --usergroups, from a file
+// or, for api, from Slack. It runs after the flags are parsed and checked, so
+// a failed request exits with exitSlack rather than as a flag error.
+func applyUsergroups(flags *flag.FlagSet, opts *slackify.Options) {
+	value := flags.Lookup("usergroups").Value.String()
+	switch value {
+	case "":
+		return
+	case "api":
+		groups, err := fetchUsergroups()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error fetching Slack user groups: %v\n"), err)
+			os.Exit(exitSlack)
+		}
+		opts.Usergroups = groups
+	default:
+		groups, err := loadUsergroups(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, tr("Error: --usergroups: %v\n"), err)
+			os.Exit(exitUsage)
+		}
+		opts.Usergroups = groups
+	}
+}
+
+// emojiCodeRegex matches a single :emoji: code
+var emojiCodeRegex = regexp.MustCompile(`^:[a-z0-9_+'-]+:$`)
+
+// checkOptions exits with a usage error when an option has an invalid value
+// or conflicts with another
+func checkOptions(opts slackify.Options) {
+	if opts.PreserveSpacing && opts.Compact {
+		fmt.Fprintf(os.Stderr, tr("Error: Use either --preserve-spacing or --compact, not both\n"))
+		os.Exit(exitUsage)
+	}
+
+	if opts.Punctuation != "" && opts.Punctuation != "ascii" && opts.Punctuation != "unicode" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --punctuation value '%s' (use ascii or unicode)\n"), opts.Punctuation)
+		os.Exit(exitUsage)
+	}
+
+	if opts.Spoilers != "" && opts.Spoilers != "quote" && opts.Spoilers != "plain" && opts.Spoilers != "hide" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --spoilers value '%s' (use quote, plain or hide)\n"), opts.Spoilers)
+		os.Exit(exitUsage)
+	}
+
+	if opts.InlineFootnotes != "notes" && opts.InlineFootnotes != "parens" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --inline-footnotes value '%s' (use notes or parens)\n"), opts.InlineFootnotes)
+		os.Exit(exitUsage)
+	}
+
+	if opts.Highlight != "bold" && opts.Highlight != "plain" && !emojiCodeRegex.MatchString(opts.Highlight) {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --highlight value '%s' (use bold, plain or an emoji code like :star:)\n"), opts.Highlight)
+		os.Exit(exitUsage)
+	}
+
+	if opts.Abbreviations != "inline" && opts.Abbreviations != "glossary" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --abbreviations value '%s' (use inline or glossary)\n"), opts.Abbreviations)
+		os.Exit(exitUsage)
+	}
+
+	if opts.Critic != "accept" && opts.Critic != "reject" && opts.Critic != "show" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --critic value '%s' (use accept, reject or show)\n"), opts.Critic)
+		os.Exit(exitUsage)
+	}
+
+	if opts.Dialect != "" && opts.Dialect != "notion" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --dialect value '%s' (use notion)\n"), opts.Dialect)
+		os.Exit(exitUsage)
+	}
+
+	if opts.LinkStyle != "inline" && opts.LinkStyle != "slack" && opts.LinkStyle != "text" && opts.LinkStyle != "footnotes" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --links value '%s' (use inline, slack, text or footnotes)\n"), opts.LinkStyle)
+		os.Exit(exitUsage)
+	}
+
+	if opts.TableMode != "code" && opts.TableMode != "plain" && opts.TableMode != "raw" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --tables value '%s' (use code, plain or raw)\n"), opts.TableMode)
+		os.Exit(exitUsage)
+	}
+
+	headingStyles := strings.Split(opts.HeadingStyle, ",")
+	for _, style := range headingStyles {
+		style = strings.TrimSpace(style)
+		if len(headingStyles) > 3 || (style != "bold" && style != "plain" && style != "caps" && style != "unicode") {
+			fmt.Fprintf(os.Stderr, tr("Error: Invalid --heading-style value '%s' (use bold, plain, caps or unicode, or up to three separated by commas)\n"), opts.HeadingStyle)
+			os.Exit(exitUsage)
+		}
+	}
+
+	if opts.OrderedLists != "number" && opts.OrderedLists != "paren" && opts.OrderedLists != "bold" && opts.OrderedLists != "bullets" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --ordered-lists value '%s' (use number, paren, bold or bullets)\n"), opts.OrderedLists)
+		os.Exit(exitUsage)
+	}
+
+	if opts.InternalLinks != "text" && opts.InternalLinks != "marker" {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --internal-links value '%s' (use text or marker)\n"), opts.InternalLinks)
+		os.Exit(exitUsage)
+	}
+
+	if opts.MaxMessages < 0 {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --max-messages value %d (use 1 or more)\n"), opts.MaxMessages)
+		os.Exit(exitUsage)
+	}
+
+	if n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(opts.TableSort), "-")); err == nil && n < 1 {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --table-sort value '%s' (use a column header, or a column number from 1)\n"), opts.TableSort)
+		os.Exit(exitUsage)
+	}
+
+	if opts.TableLimit < 0 {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --table-limit value %d (use 1 or more)\n"), opts.TableLimit)
+		os.Exit(exitUsage)
+	}
+
+	if opts.MaxCellWidth < 0 || (opts.MaxCellWidth > 0 && opts.MaxCellWidth < 4) {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --max-cell-width value %d (use 4 or more)\n"), opts.MaxCellWidth)
+		os.Exit(exitUsage)
+	}
+
+	if opts.Indent < 1 {
+		fmt.Fprintf(os.Stderr, tr("Error: Invalid --indent value %d (use 1 or more)\n"), opts.Indent)
+		os.Exit(exitUsage)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ANSI escape sequences the terminal preview approximates Slack's formatting with
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiUnderline = "\x1b[4m"
+	ansiStrike    = "\x1b[9m"
+	ansiCyan      = "\x1b[36m"
+	ansiYellow    = "\x1b[33m"
+)
+
+// Patterns for the mrkdwn the preview renders
+var (
+	previewCodeRegex   = regexp.MustCompile("`[^`\n]+`")
+	previewLinkRegex   = regexp.MustCompile(`<([^|<>\s]+)(?:\|([^<>]+))?>`)
+	previewBoldRegex   = regexp.MustCompile(`(^|[^\w*])\*([^*\n]+)\*`)
+	previewItalicRegex = regexp.MustCompile(`(^|[^\w_])_([^_\n]+)_`)
+	previewStrikeRegex = regexp.MustCompile(`(^|[^\w~])~([^~\n]+)~`)
+)
+
+// previewANSI renders converted mrkdwn for a terminal: bold as bold, italic
+// underlined, strikethrough struck, code in cyan, link targets and quote
+// bars dimmed. Lines whose words differ from the markdown they came from,
+// not just in markup, are marked in a yellow gutter for review.
+func previewANSI(markdown, converted string) string {
+	changed := changedLines(markdown, converted)
+	var b strings.Builder
+	inCode := false
+	for i, line := range strings.Split(converted, "\n") {
+		if changed[i] {
+			b.WriteString(ansiYellow + "▌ " + ansiReset)
+		} else {
+			b.WriteString("  ")
+		}
+
+		fence := strings.HasPrefix(strings.TrimSpace(line), "```")
+		switch {
+		case inCode || fence:
+			b.WriteString(ansiCyan + line + ansiReset)
+			if fence && strings.Count(line, "```")%2 == 1 {
+				inCode = !inCode
+			}
+		case strings.HasPrefix(line, ">"):
+			b.WriteString(ansiDim + "│" + ansiReset + previewInline(strings.TrimPrefix(line, ">")))
+		default:
+			b.WriteString(previewInline(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// previewInline renders the inline mrkdwn of one line, leaving code spans'
+// contents alone
+func previewInline(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range previewCodeRegex.FindAllStringIndex(line, -1) {
+		b.WriteString(previewStyles(line[last:loc[0]]))
+		b.WriteString(ansiCyan + line[loc[0]+1:loc[1]-1] + ansiReset)
+		last = loc[1]
+	}
+	b.WriteString(previewStyles(line[last:]))
+	return b.String()
+}
+
+// previewStyles renders links, bold, italic and strikethrough in text
+func previewStyles(text string) string {
+	text = previewLinkRegex.ReplaceAllStringFunc(text, func(link string) string {
+		m := previewLinkRegex.FindStringSubmatch(link)
+		if m[2] == "" {
+			return ansiUnderline + m[1] + ansiReset
+		}
+		return ansiUnderline + m[2] + ansiReset + ansiDim + " (" + m[1] + ")" + ansiReset
+	})
+	text = previewBoldRegex.ReplaceAllString(text, "$1"+ansiBold+"$2"+ansiReset)
+	text = previewItalicRegex.ReplaceAllString(text, "$1"+ansiUnderline+"$2"+ansiReset)
+	return previewStrikeRegex.ReplaceAllString(text, "$1"+ansiStrike+"$2"+ansiReset)
+}
+
+// changedLines tells which lines of converted have words that no line of
+// markdown has at about the same place. Lines are compared by their letters
+// and digits alone, so a line whose markup alone changed, like **bold**
+// becoming *bold*, counts as unchanged.
+func changedLines(markdown, converted string) []bool {
+	words := func(line string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, line)
+	}
+	var source []string
+	for _, line := range strings.Split(markdown, "\n") {
+		if w := words(line); w != "" {
+			source = append(source, w)
+		}
+	}
+
+	// Lines keep their order, so each is looked for a little past the last
+	// match; anything further away counts as changed
+	const window = 50
+	outLines := strings.Split(converted, "\n")
+	changed := make([]bool, len(outLines))
+	next := 0
+	for i, line := range outLines {
+		w := words(line)
+		if w == "" {
+			continue
+		}
+		changed[i] = true
+		for j := next; j < min(next+window, len(source)); j++ {
+			if source[j] == w {
+				changed[i] = false
+				next = j + 1
+				break
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// isRemote reports whether an input argument is an http(s) URL to fetch
+func isRemote(arg string) bool {
+	return strings.HasPrefix(arg, "https://") || strings.HasPrefix(arg, "http://")
+}
+
+// formatFromName guesses the input format from a file name's extension,
+// returning "" when it gives no hint
+func formatFromName(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".mdown", ".mkd":
+		return "markdown"
+	case ".adoc", ".asciidoc", ".asc":
+		return "asciidoc"
+	case ".html", ".htm", ".xhtml":
+		return "html"
+	}
+	return ""
+}
+
+// fetchInput downloads a remote document. Proxies are taken from the
+// environment. The response's charset picks the encoding unless one was
+// chosen, and its media type or the URL's extension hints at the format.
+func fetchInput(rawURL string, encoding string, timeout time.Duration) (string, string, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	format := ""
+	if mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			format = "html"
+		case "text/markdown", "text/x-markdown":
+			format = "markdown"
+		case "text/asciidoc":
+			format = "asciidoc"
+		}
+		if encoding == "utf-8" {
+			switch strings.ToLower(params["charset"]) {
+			case "iso-8859-1", "latin1":
+				encoding = "latin1"
+			case "windows-1252":
+				encoding = "windows-1252"
+			}
+		}
+	}
+	if format == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			format = formatFromName(u.Path)
+		}
+	}
+
+	text, err := readInput(resp.Body, encoding)
+	return text, format, err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification without an ID
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response carrying either a result or an error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a failed JSON-RPC request
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcConvertParams are the params of the convert and convertToBlocks methods
+type rpcConvertParams struct {
+	Markdown *string `json:"markdown"`
+}
+
+// rpcConvertResult is the result of the convert method
+type rpcConvertResult struct {
+	Mrkdwn   string   `json:"mrkdwn"`
+	Warnings []string `json:"warnings"`
+}
+
+// rpcBlocksResult is the result of the convertToBlocks method
+type rpcBlocksResult struct {
+	Blocks []slackify.Block `json:"blocks"`
+}
+
+// serveJSONRPC answers JSON-RPC 2.0 requests, one per line, until the input
+// ends or a shutdown request comes. It stays running between requests, so
+// editor plugins can convert selections without starting a process each
+// time. The methods are convert and convertToBlocks, both taking
+// {"markdown"}, and shutdown.
+func serveJSONRPC(r io.Reader, w io.Writer, converter *slackify.Converter) error {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			resp, shutdown := answerRPC(line, converter)
+			if resp != nil {
+				if encErr := encoder.Encode(resp); encErr != nil {
+					return encErr
+				}
+			}
+			if shutdown {
+				return nil
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// answerRPC returns the response to one request line, nil for a
+// notification, and whether the request asks to shut down
+func answerRPC(line []byte, converter *slackify.Converter) (*rpcResponse, bool) {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return rpcFailure(nil, rpcParseError, fmt.Sprintf("invalid JSON: %v", err)), false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcFailure(req.ID, rpcInvalidRequest, "not a JSON-RPC 2.0 request"), false
+	}
+
+	var result any
+	var failure *rpcResponse
+	shutdown := false
+	switch req.Method {
+	case "convert", "convertToBlocks":
+		var params rpcConvertParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Markdown == nil {
+			failure = rpcFailure(req.ID, rpcInvalidParams, "params need a \"markdown\" string")
+			break
+		}
+		if req.Method == "convert" {
+			res := rpcConvertResult{Mrkdwn: converter.Convert(*params.Markdown), Warnings: []string{}}
+			for _, w := range converter.Check(*params.Markdown) {
+				res.Warnings = append(res.Warnings, w.String())
+			}
+			result = res
+			break
+		}
+		blocks, err := converter.ConvertToBlocks(*params.Markdown)
+		if err != nil {
+			failure = rpcFailure(req.ID, rpcInternalError, err.Error())
+			break
+		}
+		if blocks == nil {
+			blocks = []slackify.Block{}
+		}
+		result = rpcBlocksResult{blocks}
+	case "shutdown":
+		shutdown = true
+	default:
+		failure = rpcFailure(req.ID, rpcMethodNotFound, fmt.Sprintf("unknown method %q (use convert, convertToBlocks or shutdown)", req.Method))
+	}
+
+	// Notifications get no response, not even an error
+	if req.ID == nil {
+		return nil, shutdown
+	}
+	if failure != nil {
+		return failure, shutdown
+	}
+	if result == nil {
+		result = struct{}{}
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}, shutdown
+}
+
+// rpcFailure returns an error response for the request with id
+func rpcFailure(id json.RawMessage, code int, message string) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{code, message}}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpShortener shortens URLs with a web service: it fetches template with
+// {url} replaced by the escaped long URL and reads the short URL from the
+// response, either the whole plain-text body or a field of a JSON object
+type httpShortener struct {
+	template string
+	client   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// newHTTPShortener returns a shortener for template, which must be an http(s)
+// URL containing {url}
+func newHTTPShortener(template string, timeout time.Duration) (*httpShortener, error) {
+	if !isRemote(template) || !strings.Contains(template, "{url}") {
+		return nil, fmt.Errorf("shortener %q must be an http(s) URL containing {url}", template)
+	}
+	return &httpShortener{template: template, client: &http.Client{Timeout: timeout}, cache: map[string]string{}}, nil
+}
+
+// Shorten returns the short URL for long, asking the service once per URL.
+// Failures are reported on stderr, and the long URL stays.
+func (s *httpShortener) Shorten(ctx context.Context, long string) (string, error) {
+	s.mu.Lock()
+	short, ok := s.cache[long]
+	s.mu.Unlock()
+	if ok {
+		return short, nil
+	}
+
+	short, err := s.fetch(ctx, long)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Warning: Could not shorten %s: %v\n"), long, err)
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.cache[long] = short
+	s.mu.Unlock()
+	return short, nil
+}
+
+// fetch asks the service for the short URL of long
+func (s *httpShortener) fetch(ctx context.Context, long string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(s.template, "{url}", url.QueryEscape(long)), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("shortener returned %s", resp.Status)
+	}
+
+	short := strings.TrimSpace(string(body))
+	if strings.HasPrefix(short, "{") {
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", err
+		}
+		short = ""
+		for _, key := range []string{"short_url", "shorturl", "shortUrl", "link", "url"} {
+			if value, ok := fields[key].(string); ok {
+				short = value
+				break
+			}
+		}
+	}
+	if !isRemote(short) {
+		return "", fmt.Errorf("shortener returned no URL")
+	}
+	return short, nil
+}
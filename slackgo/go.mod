@@ -0,0 +1,10 @@
+module github.com/robmathews/slackify-markdown/slackgo
+
+go 1.23.2
+
+require (
+	github.com/robmathews/slackify-markdown v0.0.0-20261015110748-e45e6b89ed22
+	github.com/slack-go/slack v0.17.3
+)
+
+require github.com/gorilla/websocket v1.5.3 // indirect
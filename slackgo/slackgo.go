@@ -0,0 +1,69 @@
+// Package slackgo adapts converted markdown to the github.com/slack-go/slack
+// SDK. It is a separate module so the converter itself keeps no dependencies.
+// To build it against a working copy of the converter, create a workspace at
+// the repository root with "go work init . ./slackgo".
+package slackgo
+
+import (
+	"encoding/json"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+	"github.com/slack-go/slack"
+)
+
+// Blocks converts markdown to SDK blocks, all of them; slackify.SplitBlocks
+// shows where to break them into messages
+func Blocks(c *slackify.Converter, markdown string) ([]slack.Block, error) {
+	blocks, err := c.ConvertToBlocks(markdown)
+	if err != nil {
+		return nil, err
+	}
+	return toSDK(blocks)
+}
+
+// MsgOptions returns the options that post markdown as one message: its
+// blocks, plus the mrkdwn text Slack shows in notifications
+func MsgOptions(c *slackify.Converter, markdown string) ([]slack.MsgOption, error) {
+	blocks, err := Blocks(c, markdown)
+	if err != nil {
+		return nil, err
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionText(c.Convert(markdown), false),
+	}, nil
+}
+
+// Messages returns the blocks of each message needed to post markdown,
+// keeping every message within Slack's block limit
+func Messages(c *slackify.Converter, markdown string) ([][]slack.Block, error) {
+	blocks, err := c.ConvertToBlocks(markdown)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages [][]slack.Block
+	for _, message := range slackify.SplitBlocks(blocks, slackify.MaxBlocks) {
+		sdkBlocks, err := toSDK(message)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, sdkBlocks)
+	}
+	return messages, nil
+}
+
+// toSDK turns slackify blocks into SDK blocks through their JSON form, which
+// both sides agree on
+func toSDK(blocks []slackify.Block) ([]slack.Block, error) {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	var sdk slack.Blocks
+	if err := json.Unmarshal(data, &sdk); err != nil {
+		return nil, err
+	}
+	return sdk.BlockSet, nil
+}
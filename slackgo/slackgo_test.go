@@ -0,0 +1,114 @@
+package slackgo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+	"github.com/slack-go/slack"
+)
+
+func TestBlocksRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, in string
+	}{
+		{"header", "# Title"},
+		{"styled text", "Some **bold**, _italic_, ~~struck~~ and `code`."},
+		{"link", "See [the docs](https://example.com/docs)."},
+		{"lists", "- one\n  - nested\n- two\n\n1. first\n2. second"},
+		{"quote", "> quoted\n> text"},
+		{"code block", "```\nfmt.Println(1)\n```"},
+		{"divider", "above\n\n---\n\nbelow"},
+		{"image", "![A chart](https://example.com/chart.png)"},
+		{"mentions", "Ping <@U123> in <#C456> :wave:"},
+	}
+	c := slackify.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := c.ConvertToBlocks(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Blocks(c, tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("Blocks(%q) returned %d blocks, want %d", tt.in, len(got), len(want))
+			}
+			for i, block := range got {
+				if _, ok := block.(*slack.UnknownBlock); ok {
+					t.Errorf("block %d (%s) is unknown to the SDK", i, want[i].Type)
+				}
+			}
+
+			if sdk, ours := jsonValue(t, got), jsonValue(t, want); !reflect.DeepEqual(sdk, ours) {
+				t.Errorf("Blocks(%q) round trip changed the blocks:\n got %s\nwant %s", tt.in, mustJSON(t, got), mustJSON(t, want))
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	c := slackify.New()
+	for _, tt := range []struct {
+		headers, want int
+	}{
+		{1, 1},
+		{slackify.MaxBlocks, 1},
+		{slackify.MaxBlocks + 1, 2},
+	} {
+		var markdown string
+		for i := 0; i < tt.headers; i++ {
+			markdown += "# Heading\n\n"
+		}
+		messages, err := Messages(c, markdown)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != tt.want {
+			t.Errorf("Messages with %d headers returned %d messages, want %d", tt.headers, len(messages), tt.want)
+		}
+	}
+}
+
+// jsonValue decodes the JSON form of v into generic maps and slices, so two
+// encodings can be compared regardless of field order. Zero numbers are
+// dropped: the SDK writes indent, offset and border even when Slack's
+// default applies.
+func jsonValue(t *testing.T, v any) any {
+	t.Helper()
+	var out any
+	if err := json.Unmarshal(mustJSON(t, v), &out); err != nil {
+		t.Fatal(err)
+	}
+	return dropZeros(out)
+}
+
+func dropZeros(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if value == float64(0) {
+				delete(v, key)
+			} else {
+				v[key] = dropZeros(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = dropZeros(value)
+		}
+	}
+	return v
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,229 @@
+package slackify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Patterns for AsciiDoc block syntax, matched a line at a time
+var (
+	asciidocHeadingRegex        = regexp.MustCompile(`^(=+)\s+(.*)$`)
+	asciidocAttributeRegex      = regexp.MustCompile(`^:([\w-]+):\s*(.*)$`)
+	asciidocUnorderedRegex      = regexp.MustCompile(`^(\*+|-)\s+(.*)$`)
+	asciidocOrderedRegex        = regexp.MustCompile(`^(\.+)\s+(.*)$`)
+	asciidocAdmonitionRegex     = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	asciidocImageRegex          = regexp.MustCompile(`^image::([^\[]+)\[([^\]]*)\]$`)
+	asciidocBlockAttributeRegex = regexp.MustCompile(`^\[[^\]]*\]$`)
+	asciidocColsRegex           = regexp.MustCompile(`cols="?(\d+)|cols="?([^"\]]+)`)
+)
+
+// asciidocToMarkdown converts the common subset of AsciiDoc (headings, lists,
+// delimited blocks, tables, admonitions, links and inline formatting) to
+// markdown, ready for Convert
+func asciidocToMarkdown(source string) (string, error) {
+	lines := strings.Split(source, "\n")
+
+	// Attribute entries may follow the title that references them
+	attributes := map[string]string{}
+	for _, line := range lines {
+		if m := asciidocAttributeRegex.FindStringSubmatch(strings.TrimRight(line, " \t")); m != nil {
+			attributes[m[1]] = m[2]
+		}
+	}
+
+	result := []string{}
+	counters := map[int]int{}
+	columns := 0
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		// Delimited blocks run until their closing delimiter
+		switch line {
+		case "////":
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "////"; i++ {
+			}
+			continue
+		case "----", "....":
+			code := []string{}
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != line; i++ {
+				code = append(code, lines[i])
+			}
+			result = append(result, "```", strings.Join(code, "\n"), "```")
+			continue
+		case "____":
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != line; i++ {
+				result = append(result, strings.TrimRight("> "+asciidocInline(lines[i], attributes), " "))
+			}
+			continue
+		case "====", "****", "--":
+			// Example, sidebar and open blocks only group their content
+			continue
+		case "|===":
+			table := []string{}
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "|==="; i++ {
+				table = append(table, lines[i])
+			}
+			result = append(result, asciidocTable(table, columns, attributes))
+			columns = 0
+			continue
+		case "'''":
+			result = append(result, "---")
+			continue
+		case "+", "<<<":
+			continue
+		}
+
+		if strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if asciidocAttributeRegex.MatchString(line) {
+			continue
+		}
+
+		if asciidocBlockAttributeRegex.MatchString(line) {
+			// Block attributes only matter for table column counts
+			if m := asciidocColsRegex.FindStringSubmatch(line); m != nil {
+				if m[1] != "" {
+					fmt.Sscanf(m[1], "%d", &columns)
+				} else {
+					columns = len(strings.Split(m[2], ","))
+				}
+			}
+			continue
+		}
+
+		if m := asciidocHeadingRegex.FindStringSubmatch(line); m != nil {
+			// The converter knows three heading levels
+			level := len(m[1])
+			if level > 3 {
+				level = 3
+			}
+			result = append(result, strings.Repeat("#", level)+" "+asciidocInline(m[2], attributes))
+			continue
+		}
+
+		if m := asciidocUnorderedRegex.FindStringSubmatch(line); m != nil {
+			depth := len(m[1])
+			if m[1] == "-" {
+				depth = 1
+			}
+			result = append(result, strings.Repeat("  ", depth-1)+"- "+asciidocInline(m[2], attributes))
+			continue
+		}
+
+		if m := asciidocOrderedRegex.FindStringSubmatch(line); m != nil {
+			depth := len(m[1])
+			counters[depth]++
+			for deeper := depth + 1; counters[deeper] > 0; deeper++ {
+				counters[deeper] = 0
+			}
+			item := fmt.Sprintf("%d. %s", counters[depth], asciidocInline(m[2], attributes))
+			result = append(result, strings.Repeat("  ", depth-1)+item)
+			continue
+		}
+
+		if m := asciidocAdmonitionRegex.FindStringSubmatch(line); m != nil {
+			// Rendered through the callout support as a GitHub-style alert
+			result = append(result, "> [!"+m[1]+"]", "> "+asciidocInline(m[2], attributes))
+			continue
+		}
+
+		if m := asciidocImageRegex.FindStringSubmatch(line); m != nil {
+			alt := m[2]
+			if alt == "" {
+				alt = "image"
+			}
+			result = append(result, "["+alt+"]("+m[1]+")")
+			continue
+		}
+
+		if line == "" {
+			counters = map[int]int{}
+		}
+		result = append(result, asciidocInline(line, attributes))
+	}
+
+	return strings.Join(result, "\n"), nil
+}
+
+// asciidocTable converts the body of a |=== table to a markdown pipe table.
+// Without a cols attribute the first row's cells set the column count.
+func asciidocTable(lines []string, columns int, attributes map[string]string) string {
+	cells := []string{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "|") {
+			// A continuation of the previous cell
+			if len(cells) > 0 {
+				cells[len(cells)-1] += " " + line
+			}
+			continue
+		}
+		rowCells := strings.Split(line[1:], "|")
+		if columns == 0 {
+			columns = len(rowCells)
+		}
+		for _, cell := range rowCells {
+			cells = append(cells, asciidocInline(strings.TrimSpace(cell), attributes))
+		}
+	}
+	if columns == 0 || len(cells) == 0 {
+		return ""
+	}
+
+	rows := []string{}
+	for start := 0; start < len(cells); start += columns {
+		end := start + columns
+		if end > len(cells) {
+			end = len(cells)
+		}
+		rows = append(rows, "| "+strings.Join(cells[start:end], " | ")+" |")
+		if start == 0 {
+			rows = append(rows, "|"+strings.Repeat("---|", columns))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// Patterns for AsciiDoc inline syntax
+var (
+	asciidocAttributeRefRegex = regexp.MustCompile(`\{([\w-]+)\}`)
+	asciidocURLLinkRegex      = regexp.MustCompile(`link:([^\s\[]+)\[([^\]]*)\]|((?:https?|ftp|mailto):[^\s\[]+)\[([^\]]*)\]`)
+	asciidocXrefRegex         = regexp.MustCompile(`<<[^,>]+,\s*([^>]+)>>|xref:[^\[]+\[([^\]]*)\]`)
+	asciidocBoldRegex         = regexp.MustCompile(`(^|[^\w*])\*([^\s*](?:[^*]*[^\s*])?)\*([^\w*]|$)`)
+	asciidocItalicRegex       = regexp.MustCompile(`__([^_]+)__`)
+	asciidocPassthroughRegex  = regexp.MustCompile(`\+([^+\s](?:[^+]*[^+\s])?)\+`)
+)
+
+// asciidocInline converts AsciiDoc inline markup to markdown: attribute
+// references, links, cross references, bold, italic and passthroughs
+func asciidocInline(text string, attributes map[string]string) string {
+	return mapOutsideCode(text, func(s string) string {
+		s = asciidocAttributeRefRegex.ReplaceAllStringFunc(s, func(m string) string {
+			if value, ok := attributes[m[1:len(m)-1]]; ok {
+				return value
+			}
+			return m
+		})
+		s = asciidocURLLinkRegex.ReplaceAllStringFunc(s, func(m string) string {
+			// link: takes any target, relative ones included; a bare URL needs a scheme
+			parts := asciidocURLLinkRegex.FindStringSubmatch(m)
+			target, text := parts[1]+parts[3], parts[2]+parts[4]
+			if text == "" {
+				return target
+			}
+			return "[" + text + "](" + target + ")"
+		})
+		s = asciidocXrefRegex.ReplaceAllString(s, "$1$2")
+		s = asciidocBoldRegex.ReplaceAllString(s, "$1**$2**$3")
+		// _italic_ means the same in markdown; only the unconstrained form changes
+		s = asciidocItalicRegex.ReplaceAllString(s, "_${1}_")
+		return asciidocPassthroughRegex.ReplaceAllString(s, "$1")
+	})
+}
@@ -0,0 +1,27 @@
+package slackify
+
+import "testing"
+
+func TestAsciidocInline(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"url", "See https://example.com[the site].", "See [the site](https://example.com)."},
+		{"url without text", "See https://example.com[].", "See https://example.com."},
+		{"link macro", "See link:https://example.com/a?b=c[docs].", "See [docs](https://example.com/a?b=c)."},
+		{"relative link", "See link:docs/a.html[docs].", "See [docs](docs/a.html)."},
+		{"relative link without text", "See link:docs/a.html[].", "See docs/a.html."},
+		{"mailto", "Mail mailto:team@example.com[us].", "Mail [us](mailto:team@example.com)."},
+		{"xref", "See <<setup,Setup>> and xref:other.adoc[Other].", "See Setup and Other."},
+		{"in code", "Run `link:docs/a.html[docs]`.", "Run `link:docs/a.html[docs]`."},
+		{"attribute", "Version {version}.", "Version 1.2."},
+		{"bold and italic", "*bold* and __italic__", "**bold** and _italic_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := asciidocInline(tt.in, map[string]string{"version": "1.2"}); got != tt.want {
+				t.Errorf("asciidocInline(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package slackify
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchDocument returns a markdown document of about size bytes, mixing the
+// constructs the stages rewrite: headings, emphasis, links, lists, quotes,
+// code blocks and tables
+func benchDocument(size int) string {
+	var b strings.Builder
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, "## Section %d\n\n", i)
+		fmt.Fprintf(&b, "Some **bold** and *italic* text with `code`, a [link](https://example.com/%d) and ~~struck~~ words.\n\n", i)
+		b.WriteString("- first item\n- second item with __bold__\n  - nested _item_\n\n")
+		b.WriteString("> A quoted line\n\n")
+		b.WriteString("```go\nfunc main() {\n\tfmt.Println(\"**not bold**\")\n}\n```\n\n")
+		b.WriteString("| Name | Value |\n|------|-------|\n| a | 1 |\n| b | 2 |\n\n")
+	}
+	return b.String()
+}
+
+func BenchmarkConvert(b *testing.B) {
+	c := New()
+	for _, size := range []int{64 << 10, 1 << 20, 4 << 20} {
+		text := benchDocument(size)
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Convert(text)
+			}
+		})
+	}
+}
+
+func BenchmarkConvertToBlocks(b *testing.B) {
+	c := New()
+	for _, size := range []int{64 << 10, 1 << 20} {
+		text := benchDocument(size)
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.ConvertToBlocks(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package slackify
+
+import "encoding/json"
+
+// Block is a Block Kit block. Converted markdown uses rich_text blocks,
+// divider and image blocks, and sections for images Slack cannot fetch.
+type Block struct {
+	Type     string            `json:"type"`
+	Elements []RichTextElement `json:"elements,omitempty"`
+
+	// Image blocks
+	ImageURL string      `json:"image_url,omitempty"`
+	AltText  string      `json:"alt_text,omitempty"`
+	Title    *TextObject `json:"title,omitempty"`
+
+	// Section blocks
+	Text *TextObject `json:"text,omitempty"`
+}
+
+// TextObject is a Block Kit text object, "plain_text" or "mrkdwn"
+type TextObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// TextStyle is the style of an inline rich text element
+type TextStyle struct {
+	Bold   bool `json:"bold,omitempty"`
+	Italic bool `json:"italic,omitempty"`
+	Strike bool `json:"strike,omitempty"`
+	Code   bool `json:"code,omitempty"`
+}
+
+// RichTextElement is an element of a rich_text block: a section, list,
+// preformatted block or quote holding inline elements, or an inline text or
+// link. ListStyle ("bullet" or "ordered") and TextStyle are both written as
+// Slack's "style" field.
+type RichTextElement struct {
+	Type     string            `json:"type"`
+	Elements []RichTextElement `json:"elements,omitempty"`
+
+	// Lists
+	ListStyle string `json:"-"`
+	Indent    int    `json:"indent,omitempty"`
+	Offset    int    `json:"offset,omitempty"`
+
+	// Inline text and links
+	Text      string     `json:"text,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	TextStyle *TextStyle `json:"-"`
+
+	// Mentions and emoji
+	UserID      string `json:"user_id,omitempty"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	UsergroupID string `json:"usergroup_id,omitempty"`
+	Range       string `json:"range,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// MarshalJSON writes the list style or text style as Slack's "style" field,
+// which is a string for lists and an object for inline elements
+func (e RichTextElement) MarshalJSON() ([]byte, error) {
+	type plain RichTextElement
+	var style any
+	if e.ListStyle != "" {
+		style = e.ListStyle
+	} else if e.TextStyle != nil && *e.TextStyle != (TextStyle{}) {
+		style = e.TextStyle
+	}
+	return json.Marshal(struct {
+		plain
+		Style any `json:"style,omitempty"`
+	}{plain(e), style})
+}
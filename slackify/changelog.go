@@ -0,0 +1,59 @@
+package slackify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Patterns for changelog release headings and link reference definitions
+var (
+	changelogHeadingRegex   = regexp.MustCompile(`^##[ \t]+\[?v?([^\]\s]+)\]?(.*)$`)
+	changelogReferenceRegex = regexp.MustCompile(`(?m)^\[v?([^\]]+)\]:[ \t]*(\S+)`)
+)
+
+// ChangelogSection extracts the section for version from a Keep a Changelog
+// style document: its "## [1.2.3] - date" heading and everything up to the
+// next release. A leading "v" is optional, and "latest" picks the newest
+// released version, skipping [Unreleased].
+func ChangelogSection(text string, version string) (string, error) {
+	want := strings.TrimPrefix(version, "v")
+	lines := strings.Split(normalizeLineEndings(text), "\n")
+
+	start, end := -1, len(lines)
+	found := ""
+	for i, line := range lines {
+		match := changelogHeadingRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if start >= 0 {
+			end = i
+			break
+		}
+		name := match[1]
+		if (want == "latest" && !strings.EqualFold(name, "unreleased")) || strings.EqualFold(name, want) {
+			start, found = i, name
+		}
+	}
+	if start < 0 {
+		return "", fmt.Errorf("no changelog section for %s", version)
+	}
+
+	section := strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+
+	// Link the version in the heading when the changelog defines where it points
+	for _, ref := range changelogReferenceRegex.FindAllStringSubmatch(text, -1) {
+		if ref[1] == found {
+			heading, rest, _ := strings.Cut(section, "\n")
+			for _, label := range []string{"[" + found + "]", "[v" + found + "]"} {
+				heading = strings.ReplaceAll(heading, label, label+"("+ref[2]+")")
+			}
+			section = strings.TrimSpace(heading + "\n" + rest)
+			break
+		}
+	}
+
+	// Reference definitions after the last section are not part of it
+	return strings.TrimSpace(changelogReferenceRegex.ReplaceAllString(section, "")), nil
+}
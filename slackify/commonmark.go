@@ -0,0 +1,209 @@
+package slackify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// With Options.CommonMarkBlocks, the CommonMark block constructs the
+// line-based stages miss are normalized into the markdown they convert. Each
+// node type maps to Slack output as follows:
+//
+//   - ATX and setext headings: a bold line in the --heading-style; levels 4
+//     to 6 are rendered like level 3
+//   - paragraphs: their text, with lazy continuation lines kept in their
+//     block quote or list item
+//   - block quotes: indented text, four more spaces per nesting level
+//   - bullet lists (-, * or +): bullets by nesting level; ordered lists:
+//     renumbered markers in the --ordered-lists style
+//   - fenced and indented code blocks: ``` code blocks
+//   - thematic breaks and HTML blocks: left as written, or shown verbatim
+//     with --code-fallback
+//   - link reference definitions: removed, with [text][label], [label][]
+//     and [label] references turned into links
+//   - emphasis, strong emphasis and code spans: _italic_, *bold* and `code`
+//   - links, images and autolinks: as --links renders them
+//   - hard line breaks: newlines
+//
+// This is not a full CommonMark parser. HTML blocks are not recognized, so
+// the lines inside them are rewritten like any other. List items hold one
+// paragraph: blocks indented under an item after a blank line stay
+// indented text, and code among them is not fenced. Link reference
+// definitions must fit on one line.
+
+// Patterns for the CommonMark block structure
+var (
+	atxHeadingRegex     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))??(?:[ \t]+#+)?[ \t]*$`)
+	setextRegex         = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	quotePrefixRegex    = regexp.MustCompile(`^(?: {0,3}>[ ]?)+`)
+	bulletMarkerRegex   = regexp.MustCompile(`^(\s*)[*+]([ \t]+)`)
+	itemContentRegex    = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])[ \t]+)\S`)
+	referenceDefRegex   = regexp.MustCompile(`(?m)^ {0,3}\[([^\]^][^\]]*)\]:[ \t]*<?([^\s>]+)>?(?:[ \t]+(?:"[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*(?:\n|$)`)
+	referenceLinkRegex  = regexp.MustCompile(`\[([^\]]+)\](?:\[([^\]]*)\])?`)
+	indentedLineRegex   = regexp.MustCompile(`^(?: {4}|\t)`)
+	blockStartRegex     = regexp.MustCompile("^(?:\\s*(?:[-*+][ \t]|\\d+[.)][ \t])| {0,3}(?:#{1,6}(?:[ \t]|$)|>|```|~~~|<))")
+	setextParagraphSkip = regexp.MustCompile(`^\s*$|^ {0,3}(?:[-*+][ \t]|\d+[.)][ \t]|#|>|\|)`)
+)
+
+// normalizeCommonMark rewrites the CommonMark constructs the core stages do
+// not know into ones they do: indented code, link references, setext and
+// deep headings, * and + bullets, nested quotes and lazy continuation lines
+func normalizeCommonMark(text string) string {
+	text = mapOutsideFences(text, fenceIndentedCode)
+	text = resolveReferenceLinks(text)
+	return mapOutsideFences(text, normalizeBlocks)
+}
+
+// fenceIndentedCode turns code blocks indented by four spaces into fenced
+// blocks. Indented lines in a list continue the list instead.
+func fenceIndentedCode(text string) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	blank, inList := true, false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if !blank || inList || !indentedLineRegex.MatchString(line) || strings.TrimSpace(line) == "" {
+			if strings.TrimSpace(line) != "" {
+				switch {
+				case listItemRegex.MatchString(line):
+					inList = true
+				case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+					inList = false
+				}
+			}
+			blank = strings.TrimSpace(line) == ""
+			out = append(out, line)
+			continue
+		}
+
+		// The block runs to the last indented line before an unindented one
+		end := i
+		for j := i; j < len(lines); j++ {
+			if indentedLineRegex.MatchString(lines[j]) {
+				end = j
+			} else if strings.TrimSpace(lines[j]) != "" {
+				break
+			}
+		}
+		out = append(out, "```")
+		for _, code := range lines[i : end+1] {
+			code = strings.TrimPrefix(code, "\t")
+			if len(code) >= 4 && strings.TrimSpace(code[:4]) == "" {
+				code = code[4:]
+			}
+			out = append(out, code)
+		}
+		out = append(out, "```")
+		i = end
+		blank = false
+	}
+	return strings.Join(out, "\n")
+}
+
+// resolveReferenceLinks removes link reference definitions outside code and
+// turns the [text][label], [label][] and [label] references to them into
+// inline links. Labels match case-insensitively, and the first definition
+// of a label wins.
+func resolveReferenceLinks(text string) string {
+	normalize := func(label string) string {
+		return strings.ToLower(strings.Join(strings.Fields(label), " "))
+	}
+
+	targets := map[string]string{}
+	text = mapOutsideFences(text, func(s string) string {
+		return referenceDefRegex.ReplaceAllStringFunc(s, func(def string) string {
+			m := referenceDefRegex.FindStringSubmatch(def)
+			if _, ok := targets[normalize(m[1])]; !ok {
+				targets[normalize(m[1])] = m[2]
+			}
+			return ""
+		})
+	})
+	if len(targets) == 0 {
+		return text
+	}
+
+	return mapOutsideCode(text, func(s string) string {
+		var b strings.Builder
+		last := 0
+		for _, loc := range referenceLinkRegex.FindAllStringSubmatchIndex(s, -1) {
+			label := s[loc[2]:loc[3]]
+			if loc[4] >= 0 && loc[5] > loc[4] {
+				label = s[loc[4]:loc[5]]
+			}
+			// [label] alone is a shortcut reference unless something follows it
+			if loc[4] < 0 && loc[1] < len(s) && strings.ContainsRune("([:", rune(s[loc[1]])) {
+				continue
+			}
+			target, ok := targets[normalize(label)]
+			if !ok {
+				continue
+			}
+			b.WriteString(s[last:loc[0]])
+			b.WriteString("[" + s[loc[2]:loc[3]] + "](" + target + ")")
+			last = loc[1]
+		}
+		b.WriteString(s[last:])
+		return b.String()
+	})
+}
+
+// normalizeBlocks rewrites headings, bullets and quotes line by line,
+// keeping lazy continuation lines in the quote or list item they continue
+func normalizeBlocks(text string) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	// continuation is the prefix a lazy line takes: the open quote's marker or
+	// the open list item's indent, and paragraph tells whether one is open
+	continuation, paragraph := "", false
+
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+			continuation, paragraph = "", false
+
+		case atxHeadingRegex.MatchString(line):
+			if m := atxHeadingRegex.FindStringSubmatch(line); m[2] != "" {
+				line = strings.Repeat("#", min(len(m[1]), 3)) + " " + m[2]
+			}
+			continuation, paragraph = "", false
+
+		case setextRegex.MatchString(line) && paragraph && continuation == "" &&
+			len(out) > 0 && !setextParagraphSkip.MatchString(out[len(out)-1]):
+			level := "# "
+			if strings.TrimSpace(line)[0] == '-' {
+				level = "## "
+			}
+			out[len(out)-1] = level + strings.TrimSpace(out[len(out)-1])
+			paragraph = false
+			continue
+
+		case quotePrefixRegex.MatchString(line):
+			prefix := quotePrefixRegex.FindString(line)
+			depth := strings.Count(prefix, ">")
+			content := line[len(prefix):]
+			line = "> " + strings.Repeat("    ", depth-1) + content
+			continuation = "> " + strings.Repeat("    ", depth-1)
+			paragraph = strings.TrimSpace(content) != ""
+
+		case paragraph && continuation != "" && !blockStartRegex.MatchString(line) && !ruleRegex.MatchString(line):
+			// A lazy continuation line
+			line = continuation + strings.TrimLeft(line, " \t")
+
+		default:
+			if !ruleRegex.MatchString(line) {
+				line = bulletMarkerRegex.ReplaceAllString(line, "${1}-$2")
+			}
+			continuation, paragraph = "", !ruleRegex.MatchString(line)
+			if m := itemContentRegex.FindStringSubmatch(line); m != nil {
+				continuation = strings.Repeat(" ", len(m[1]))
+			} else if strings.HasPrefix(line, " ") && len(out) > 0 {
+				// Indented lines continue the item above them
+				continuation = strings.Repeat(" ", len(line)-len(strings.TrimLeft(line, " ")))
+			}
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,37 @@
+package slackify
+
+import "testing"
+
+func TestNormalizeCommonMark(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"setext level 1", "Title\n=====", "# Title"},
+		{"setext level 2", "Title\n-----", "## Title"},
+		{"rule after blank line", "Text\n\n---", "Text\n\n---"},
+		{"deep heading", "#### Deep", "### Deep"},
+		{"closing hashes", "## Title ##", "## Title"},
+		{"indented code", "Text\n\n    code\n    more", "Text\n\n```\ncode\nmore\n```"},
+		{"indented code keeps blank lines", "    a\n\n    b", "```\na\n\nb\n```"},
+		{"indented list continuation", "- item\n\n    more", "- item\n\n    more"},
+		{"star bullets", "* one\n+ two", "- one\n- two"},
+		{"nested quote", "> > inner", ">     inner"},
+		{"lazy quote line", "> quoted\nlazy", "> quoted\n> lazy"},
+		{"lazy list line", "- item\nlazy", "- item\n  lazy"},
+		{"quote ends at blank line", "> quoted\n\nafter", "> quoted\n\nafter"},
+		{"reference link", "[text][ref]\n\n[ref]: https://example.com", "[text](https://example.com)\n\n"},
+		{"collapsed reference", "[Ref][]\n\n[ref]: https://example.com", "[Ref](https://example.com)\n\n"},
+		{"shortcut reference", "[ref]\n\n[ref]: <https://example.com> \"Title\"", "[ref](https://example.com)\n\n"},
+		{"first definition wins", "[a]\n\n[a]: https://one\n[a]: https://two", "[a](https://one)\n\n"},
+		{"undefined reference", "[nothing]", "[nothing]"},
+		{"inline link untouched", "[ref](https://inline)\n\n[ref]: https://example.com", "[ref](https://inline)\n\n"},
+		{"fenced code untouched", "```\n* star\n[ref]: https://example.com\n```", "```\n* star\n[ref]: https://example.com\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCommonMark(tt.in); got != tt.want {
+				t.Errorf("normalizeCommonMark(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,872 @@
+// Package slackify converts Markdown to Slack mrkdwn.
+package slackify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options controls the optional parts of the conversion
+type Options struct {
+	// Punctuation normalizes smart punctuation: "ascii", "unicode" or "" to leave it alone
+	Punctuation string
+	// PreserveSpacing keeps blank lines and trailing whitespace exactly as written
+	PreserveSpacing bool
+	// Compact collapses runs of blank lines and trims trailing whitespace
+	Compact bool
+	// Reflow joins hard-wrapped paragraph lines into single lines
+	Reflow bool
+	// TruncateAt limits the output to this many characters (0 means no limit)
+	TruncateAt int
+	// MoreURL is linked after truncated output as the place to read the rest
+	MoreURL string
+	// Index leads output split into several messages with one listing the
+	// section titles and the message each starts in
+	Index bool
+	// MaxMessages keeps split output to this many messages, noting how many
+	// were left out (0 means no limit)
+	MaxMessages int
+	// PartNumbers starts each message of split output with a subdued
+	// "part 2/5" label, so readers can tell the order and whether all arrived
+	PartNumbers bool
+	// Footer ends each message of split output with a subdued line of
+	// mrkdwn, like who posted it and from which file
+	Footer string
+	// Spoilers renders ||spoiler|| and <details> blocks: "quote", "plain",
+	// "hide" or "" to leave them alone
+	Spoilers string
+	// SpoilerLabel introduces a spoiler (default ":no_entry_sign: *Spoiler*")
+	SpoilerLabel string
+	// InlineFootnotes renders Pandoc ^[inline footnotes]: "notes" or "" adds
+	// them to the numbered notes at the end with the other footnotes, and
+	// "parens" keeps them in place as (parenthetical) text. With the
+	// footnotes extension off they are always parenthetical.
+	InlineFootnotes string
+	// Highlight renders ==highlighted== text: "bold" or "", "plain", or an
+	// emoji code like :large_yellow_circle: placed on both sides
+	Highlight string
+	// WikiURL turns [[wiki links]] into links using {page} and {slug}
+	// placeholders; when empty they become plain text
+	WikiURL string
+	// Dialect normalizes the quirks of a markdown flavor before conversion:
+	// "notion" or "" for plain markdown
+	Dialect string
+	// CommonMarkBlocks rewrites the CommonMark block constructs the
+	// line-based parsing misses, like setext headings, indented code, link
+	// reference definitions and lazy continuation lines, into ones it knows.
+	// It is not a full CommonMark parser; see commonmark.go for what it
+	// leaves out.
+	CommonMarkBlocks bool
+	// Critic resolves CriticMarkup: "accept", "reject", "show" or "" to leave it alone
+	Critic string
+	// Abbreviations places *[ABBR]: definitions "inline" at their first use or
+	// in a "glossary" at the end; "" leaves them alone
+	Abbreviations string
+	// LinkStyle renders [text](url) links: "inline" for text (url), "slack"
+	// for <url|text> or "text" to drop the URL; "" means inline
+	LinkStyle string
+	// Bullets are the unordered list markers by nesting level (default • and ◦)
+	Bullets []string
+	// OrderedLists renders ordered list markers as "number" (1.), "paren"
+	// (1)), "bold" (*1.*) or "bullets" like unordered lists (default number)
+	OrderedLists string
+	// Indent is the number of spaces per list nesting level in the output
+	// (default 2)
+	Indent int
+	// TableMode renders tables: "code" for an aligned code block, "plain" for
+	// aligned text without the fence or "raw" to leave them alone; "" means code
+	TableMode string
+	// TableIsolates wraps right-to-left table cells in Unicode directional
+	// isolates instead of ending them with a left-to-right mark
+	TableIsolates bool
+	// TableColumns picks the table columns to show, in order, by header or
+	// 1-based index; entries starting with - leave a column out instead
+	TableColumns []string
+	// TableSort sorts table rows by a column, named by header or 1-based
+	// index, descending when it starts with -. Tables without the column
+	// stay unsorted, and Check warns about them as it does about tables
+	// without a column of TableColumns.
+	TableSort string
+	// TableLimit keeps the first N rows of each table, noting how many more
+	// there were (0 means all)
+	TableLimit int
+	// MaxCellWidth cuts table cells wider than this many columns short with
+	// an ellipsis (0 means no limit)
+	MaxCellWidth int
+	// CellFootnotes lists the full value of each cut cell under its table
+	CellFootnotes bool
+	// GFM picks the GitHub Flavored Markdown extensions to convert, from
+	// GFMExtensions: names with a leading - are turned off, and listing any
+	// name without - turns off the ones not listed; empty means all.
+	// Strikethrough and tables apply to mrkdwn output only.
+	GFM []string
+	// HeadingStyle renders headings: "bold", "plain", "caps", which
+	// upper-cases the first two levels, or "unicode", which underlines
+	// top-level headings; "" means bold. A comma-separated list sets each
+	// level, like "caps,bold,plain".
+	HeadingStyle string
+	// TicketLinks link ticket references like JIRA-123 or #789 to their
+	// tracker as Slack links, outside code and existing links
+	TicketLinks []TicketLink
+	// CommitURL links commit SHAs of 7 to 40 hex digits, shown by their
+	// first 7, to this URL template, with {sha} replaced by the full SHA
+	CommitURL string
+	// Usergroups maps user group handles, without the @, to their IDs, so
+	// @platform-team becomes the <!subteam^S123|@platform-team> mention
+	Usergroups map[string]string
+	// Glossary replaces terms, as whole words, with the organization's
+	// naming after conversion, leaving code and URLs alone
+	Glossary map[string]string
+	// BlockedHosts are hosts, like jira.corp.example or *.internal, whose
+	// links are removed, keeping their text
+	BlockedHosts []string
+	// AllowedHosts, when set, are the only hosts links may point at
+	AllowedHosts []string
+	// InternalLinks renders removed links as their "text" alone or with a
+	// "marker" saying an internal link was removed; "" means text
+	InternalLinks string
+	// CleanURLs removes tracking parameters like utm_source and gclid
+	// from URLs
+	CleanURLs bool
+	// Shortener, when set, shortens URLs longer than ShortenOver characters
+	// (default 60)
+	Shortener   Shortener
+	ShortenOver int
+	// Redact masks tokens, keys and other credentials, including those
+	// matching SecretPatterns, as [REDACTED]
+	Redact bool
+	// SecretPatterns are extra regular expressions for credentials; ones
+	// that do not compile are ignored
+	SecretPatterns []string
+	// CodeFallback shows blocks Slack cannot represent, like raw HTML or
+	// deeply nested lists and quotes, verbatim in code blocks
+	CodeFallback bool
+	// ASCII keeps the output to plain ASCII for screen readers and limited
+	// terminals: - bullets, ASCII punctuation and ellipses, no inserted emoji
+	// and no Unicode script or underline characters
+	ASCII bool
+	// NoEmoji removes Unicode emoji and :shortcode: emoji outside code,
+	// both those in the input and those the conversion would insert
+	NoEmoji bool
+	// Escape escapes &, < and > the way Slack requires, leaving Slack's own
+	// <url|text> links, mentions and line-leading quote markers alone
+	Escape bool
+	// InlineSyntax is custom inline markup, matched outside code before any
+	// other conversion
+	InlineSyntax []InlineSyntax
+	// Trace receives a log of what each conversion stage changed
+	Trace io.Writer
+}
+
+// InlineSyntax is a custom inline construct, like {{user:alice}} or
+// #INC-123. Text matching Pattern outside code is replaced by what Render
+// returns for the match and its submatches, which is Slack mrkdwn that no
+// other conversion touches.
+type InlineSyntax struct {
+	Pattern *regexp.Regexp
+	Render  func(match []string) string
+}
+
+// codeRegex finds fenced code blocks and inline code spans
+var codeRegex = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`")
+
+// mapOutsideCode applies fn to every part of text that is not a fenced code
+// block or an inline code span
+func mapOutsideCode(text string, fn func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range codeRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(fn(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(fn(text[last:]))
+	return b.String()
+}
+
+// fencedBlockRegex finds fenced code blocks, fences included
+var fencedBlockRegex = regexp.MustCompile("(?ms)^[ \t]*(```|~~~).*?^[ \t]*(```|~~~)[ \t]*$")
+
+// mapOutsideFences applies fn to the text outside fenced code blocks. Unlike
+// mapOutsideCode it leaves inline code to fn and always splits at line
+// boundaries, so line-anchored patterns still work.
+func mapOutsideFences(text string, fn func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range fencedBlockRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(fn(text[last:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(fn(text[last:]))
+	return b.String()
+}
+
+// tracer logs what each conversion stage changed, for --debug
+type tracer struct {
+	w    io.Writer
+	last string
+}
+
+// stage logs the change the named stage made since the previous stage
+func (t *tracer) stage(name string, text string) {
+	if t.w == nil {
+		return
+	}
+	if text == t.last {
+		fmt.Fprintf(t.w, "[%s] unchanged\n", name)
+		return
+	}
+
+	before, after := diffSnippets(t.last, text, 30)
+	fmt.Fprintf(t.w, "[%s] %+d bytes\n  before: %q\n  after:  %q\n", name, len(text)-len(t.last), before, after)
+	t.last = text
+}
+
+// diffSnippets returns the first region where before and after differ,
+// with up to context characters around it
+func diffSnippets(before, after string, context int) (string, string) {
+	a, b := []rune(before), []rune(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	snippet := func(r []rune) string {
+		start := max(prefix-context, 0)
+		end := min(len(r)-suffix+context, len(r), prefix+4*context)
+		s := string(r[start:end])
+		if start > 0 {
+			s = "\u2026" + s
+		}
+		if end < len(r) {
+			s += "\u2026"
+		}
+		return s
+	}
+	return snippet(a), snippet(b)
+}
+
+// stage is one step of the conversion, applied to the whole document
+type stage struct {
+	name    string
+	enabled bool
+	run     func(string) string
+}
+
+// Convert converts markdown text to Slack mrkdwn. Converting many documents
+// with the same options is cheaper through a Converter from New.
+func Convert(text string, opts Options) string {
+	return New(WithOptions(opts)).Convert(text)
+}
+
+// ConvertWithWarnings converts markdown text to Slack mrkdwn and reports what
+// the conversion dropped or could not represent, and any credentials found
+func ConvertWithWarnings(text string, opts Options) (string, []Warning, error) {
+	return New(WithOptions(opts)).ConvertWithWarnings(text)
+}
+
+// ConvertContext reads markdown from r and writes Slack mrkdwn to w. The
+// conversion stops with the context's error once ctx is done.
+func ConvertContext(ctx context.Context, r io.Reader, w io.Writer, opts Options) error {
+	return New(WithOptions(opts)).ConvertContext(ctx, r, w)
+}
+
+// passthroughPattern matches a region between <!-- slackify:off --> and
+// <!-- slackify:on -->, or the end of the text when it is never turned back on
+const passthroughPattern = `(?s)<!--\s*slackify:off\s*-->\n?(.*?)(?:<!--\s*slackify:on\s*-->\n?|$)`
+
+// shortcodePattern matches Slack :emoji: codes
+const shortcodePattern = `\B:(?:[a-z0-9][a-z0-9_+'-]*|\+1):\B`
+
+// slackMarkupPattern matches markup already written for Slack: mentions like
+// <@U123>, <#C456|general> and <!here>, <url|text> links and :emoji: codes
+const slackMarkupPattern = `<(?:[@#!]|https?://|mailto:)[^<>\s|]*(?:\|[^<>\n]*)?>|` + shortcodePattern
+
+// Patterns for the text holdPassthrough sets aside
+var (
+	passthroughRegex = regexp.MustCompile(passthroughPattern)
+	slackMarkupRegex = regexp.MustCompile(slackMarkupPattern)
+)
+
+// holdPassthrough swaps the slackify:off regions, the rendered custom inline
+// syntax and existing Slack markup for placeholders no stage touches,
+// returning them for restorePassthrough. With opts.NoEmoji, :emoji: codes
+// outside code are removed instead of held.
+func holdPassthrough(text string, opts Options) (string, []string) {
+	var regions []string
+	hold := func(region string) string {
+		regions = append(regions, region)
+		return fmt.Sprintf("\uE000%d\uE001", len(regions)-1)
+	}
+	text = passthroughRegex.ReplaceAllStringFunc(text, func(match string) string {
+		return hold(passthroughRegex.FindStringSubmatch(match)[1])
+	})
+	if opts.NoEmoji {
+		text = mapOutsideCode(text, stripEmoji)
+	}
+	for _, custom := range opts.InlineSyntax {
+		text = mapOutsideCode(text, func(s string) string {
+			return custom.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+				return hold(custom.Render(custom.Pattern.FindStringSubmatch(match)))
+			})
+		})
+	}
+	return slackMarkupRegex.ReplaceAllStringFunc(text, hold), regions
+}
+
+// placeholderRegex finds the placeholders holdPassthrough leaves
+var placeholderRegex = regexp.MustCompile("\uE000(\\d+)\uE001")
+
+// restorePassthrough puts the regions held by holdPassthrough back
+func restorePassthrough(text string, regions []string) string {
+	if len(regions) == 0 {
+		return text
+	}
+	return placeholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		n, err := strconv.Atoi(placeholder[len("\uE000") : len(placeholder)-len("\uE001")])
+		if err != nil || n >= len(regions) {
+			return placeholder
+		}
+		return regions[n]
+	})
+}
+
+// runStages runs the conversion stages in order, checking ctx between them.
+// Stages never modify shared state, so one list can serve concurrent calls.
+func runStages(ctx context.Context, text string, stages []stage, opts Options) (string, error) {
+	text, regions := holdPassthrough(text, opts)
+	trace := &tracer{w: opts.Trace, last: text}
+
+	for _, s := range stages {
+		if !s.enabled {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		// Truncation measures the text as posted, with the held regions back
+		if s.name == "truncate" {
+			text, regions = restorePassthrough(text, regions), nil
+		}
+		// Shortening calls out to a service, under the conversion's context
+		if s.name == "shorten urls" {
+			text = mapOutsideCode(text, func(s string) string {
+				return shortenURLs(ctx, s, opts.Shortener, opts.ShortenOver)
+			})
+		} else {
+			text = s.run(text)
+		}
+		trace.stage(s.name, text)
+	}
+
+	return restorePassthrough(text, regions), nil
+}
+
+// Patterns for the markdown the stages rewrite directly
+var (
+	kbdRegex        = regexp.MustCompile(`(?i)<kbd>\s*(.*?)\s*</kbd>`)
+	codeBlockRegex  = regexp.MustCompile("(?s)```\\w*\\n(.*?)```")
+	linkRegex       = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	blockquoteRegex = regexp.MustCompile(`(?m)^> `)
+)
+
+// stages lists the conversion steps for opts. Order matters: the input is
+// normalized first, extension syntax is resolved before the core markdown
+// rewrites, and output-shaping steps run last. Patterns that depend on opts,
+// like list markers and secret rules, are compiled here, once per list.
+func stages(opts Options) []stage {
+	if opts.ASCII {
+		opts = asciiOptions(opts)
+	}
+	// Without emoji, callouts and task lists take their ASCII form
+	plain := opts.ASCII || opts.NoEmoji
+	if opts.NoEmoji && opts.SpoilerLabel == "" {
+		opts.SpoilerLabel = "*Spoiler*"
+	}
+	if plain && opts.Highlight != "plain" {
+		opts.Highlight = "bold"
+	}
+	lists := listRules(opts.Bullets, opts.Indent)
+	secrets := secretRules(opts.SecretPatterns)
+	glossary := glossaryRegex(opts.Glossary)
+	tickets := ticketRules(opts.TicketLinks)
+
+	return []stage{
+		{"line endings", true, normalizeLineEndings},
+		{"redact", opts.Redact, func(text string) string {
+			return redactSecrets(text, secrets)
+		}},
+		{"code fallback", opts.CodeFallback, func(text string) string {
+			levels := len(opts.Bullets)
+			if levels == 0 {
+				levels = 2
+			}
+			return codeFallback(text, levels)
+		}},
+
+		// Smart punctuation, before any markup is rewritten
+		{"punctuation", opts.Punctuation != "", func(text string) string {
+			return normalizePunctuation(text, opts.Punctuation)
+		}},
+		{"notion", opts.Dialect == "notion", func(text string) string {
+			return normalizeNotion(text, opts.PreserveSpacing)
+		}},
+		{"commonmark blocks", opts.CommonMarkBlocks, normalizeCommonMark},
+		{"reflow", opts.Reflow, reflowParagraphs},
+		{"hard breaks", true, convertHardBreaks},
+
+		// Keyboard keys: <kbd>Ctrl</kbd> -> `Ctrl`
+		{"kbd", true, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return kbdRegex.ReplaceAllString(s, "`$1`")
+			})
+		}},
+
+		// Superscript and subscript: ^sup^, ~sub~, <sup> and <sub>
+		{"scripts", true, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return convertScripts(s, opts.ASCII)
+			})
+		}},
+
+		// Abbreviations: *[HTML]: HyperText Markup Language
+		{"abbreviations", opts.Abbreviations != "", func(text string) string {
+			return convertAbbreviations(text, opts.Abbreviations, opts.PreserveSpacing)
+		}},
+
+		// CriticMarkup: {++add++}, {--del--}, {~~old~>new~~}, {==mark==}, {>>note<<}
+		{"critic markup", opts.Critic != "", func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return convertCriticMarkup(s, opts.Critic)
+			})
+		}},
+
+		// GFM task lists: - [ ] todo, - [x] done
+		{"task lists", gfmEnabled(opts.GFM, "tasklists"), func(text string) string {
+			return mapOutsideFences(text, func(s string) string {
+				return convertTaskLists(s, plain)
+			})
+		}},
+
+		// Inline footnotes: text^[note] -> text[^n] with a definition, or text (note)
+		{"inline footnotes", true, func(text string) string {
+			return convertInlineNotes(text, opts.InlineFootnotes != "parens" && gfmEnabled(opts.GFM, "footnotes"), opts.PreserveSpacing)
+		}},
+
+		// GFM footnotes: [^1] references and [^1]: definitions
+		{"footnotes", gfmEnabled(opts.GFM, "footnotes"), func(text string) string {
+			return convertFootnotes(text, opts.PreserveSpacing)
+		}},
+
+		// HTML tables -> markdown tables, formatted by the tables stage
+		{"html tables", true, func(text string) string {
+			return mapOutsideCode(text, convertHTMLTables)
+		}},
+
+		// Pandoc grid tables -> pipe tables
+		{"grid tables", true, func(text string) string {
+			return mapOutsideFences(text, convertGridTables)
+		}},
+
+		// Highlights: ==text== -> *text*
+		{"highlights", true, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return convertHighlights(s, opts.Highlight)
+			})
+		}},
+
+		{"clean urls", opts.CleanURLs, func(text string) string {
+			return mapOutsideCode(text, cleanURLs)
+		}},
+
+		// Links to internal hosts -> their text
+		{"internal links", len(opts.BlockedHosts) > 0 || len(opts.AllowedHosts) > 0, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return filterLinks(s, opts)
+			})
+		}},
+
+		// Shortened after internal links are gone, since a short URL hides its
+		// host. runStages runs it, passing the shortener the context.
+		{"shorten urls", opts.Shortener != nil, nil},
+
+		// GFM autolinks: www.example.com -> <http://www.example.com|www.example.com>
+		{"autolinks", gfmEnabled(opts.GFM, "autolinks"), func(text string) string {
+			return mapOutsideCode(text, convertAutolinks)
+		}},
+
+		// Ticket references: JIRA-123 -> <https://jira.example.com/browse/JIRA-123|JIRA-123>
+		{"tickets", len(tickets) > 0, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return linkTickets(s, tickets)
+			})
+		}},
+
+		// Commits: 4f2a9c1e... -> <https://github.com/o/r/commit/4f2a9c1e...|4f2a9c1>
+		{"commits", opts.CommitURL != "", func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return linkCommits(s, opts.CommitURL)
+			})
+		}},
+		{"usergroups", len(opts.Usergroups) > 0, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return mentionUsergroups(s, opts.Usergroups)
+			})
+		}},
+
+		{"headers", true, func(text string) string {
+			return mapOutsideFences(text, func(s string) string {
+				return convertHeaders(s, opts.HeadingStyle, opts.ASCII)
+			})
+		}},
+		{"emphasis", true, func(text string) string {
+			return mapOutsideFences(text, convertEmphasis)
+		}},
+		{"strikethrough", gfmEnabled(opts.GFM, "strikethrough"), func(text string) string {
+			return mapOutsideCode(text, convertStrikethrough)
+		}},
+
+		// Code blocks with language - convert to Slack snippets
+		{"code blocks", true, func(text string) string {
+			return codeBlockRegex.ReplaceAllString(text, "```$1```")
+		}},
+
+		// Inline code stays the same: `code`
+
+		{"lists", true, func(text string) string {
+			return mapOutsideFences(text, func(s string) string {
+				return convertLists(s, lists)
+			})
+		}},
+		{"ordered lists", true, func(text string) string {
+			return mapOutsideFences(text, func(s string) string {
+				return convertOrderedLists(s, opts.OrderedLists, opts.Bullets)
+			})
+		}},
+
+		// Wiki links: [[Page]] or [[Page|alias]] -> alias (url) or plain text
+		{"wiki links", true, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return convertWikiLinks(s, opts.WikiURL)
+			})
+		}},
+
+		// Links: [text](url) -> text (url), <url|text> or text
+		{"links", true, func(text string) string {
+			if opts.LinkStyle == "footnotes" {
+				return footnoteLinks(text, opts.PreserveSpacing)
+			}
+
+			replacement := "$1 ($2)"
+			switch opts.LinkStyle {
+			case "slack":
+				replacement = "<$2|$1>"
+			case "text":
+				replacement = "$1"
+			}
+			return mapOutsideFences(text, func(s string) string {
+				return linkRegex.ReplaceAllString(s, replacement)
+			})
+		}},
+
+		// Blockquotes: > text -> indented text; callouts stay quotes
+		{"blockquotes", true, func(text string) string {
+			return mapOutsideFences(text, indentBlockquotes)
+		}},
+
+		// Callouts: > [!info] Title -> emoji-labelled quote
+		{"callouts", true, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return convertCallouts(s, plain, "*")
+			})
+		}},
+
+		// Tables - convert to formatted text blocks. A fence keeps its
+		// closing line after the code blocks stage, so mapOutsideFences
+		// still finds the blocks, and tables inside them stay as written.
+		{"tables", opts.TableMode != "raw" && gfmEnabled(opts.GFM, "tables"), func(text string) string {
+			return mapOutsideFences(text, func(s string) string {
+				return convertTables(s, opts)
+			})
+		}},
+
+		// Spoilers: Slack has none, so render them as configured
+		{"spoilers", opts.Spoilers != "", func(text string) string {
+			return convertSpoilers(text, opts.Spoilers, opts.SpoilerLabel, opts.PreserveSpacing)
+		}},
+
+		{"glossary", len(opts.Glossary) > 0, func(text string) string {
+			return mapOutsideCode(text, func(s string) string {
+				return applyGlossary(s, glossary, opts.Glossary)
+			})
+		}},
+
+		// Emoji the stages above let through, like emoji bullets
+		{"no emoji", opts.NoEmoji, func(text string) string {
+			return mapOutsideCode(text, stripEmoji)
+		}},
+
+		{"escape", opts.Escape, escapeSlack},
+		{"compact", opts.Compact, compactSpacing},
+		{"truncate", opts.TruncateAt > 0, func(text string) string {
+			return truncateOutput(text, opts.TruncateAt, opts.MoreURL, ellipsis(opts))
+		}},
+	}
+}
+
+// asciiOptions fills in the defaults --ascii implies, leaving options that
+// were set explicitly alone
+func asciiOptions(opts Options) Options {
+	if len(opts.Bullets) == 0 {
+		opts.Bullets = []string{"-", "-"}
+	}
+	if opts.Punctuation == "" {
+		opts.Punctuation = "ascii"
+	}
+	if opts.SpoilerLabel == "" {
+		opts.SpoilerLabel = "*Spoiler*"
+	}
+	return opts
+}
+
+// ellipsis returns the mark for cut-off text: … or, in ASCII mode, ...
+func ellipsis(opts Options) string {
+	if opts.ASCII {
+		return "..."
+	}
+	return "\u2026"
+}
+
+// headingStyles returns the style of each heading level for style, which is
+// either one style or a comma-separated list, one per level, whose last entry
+// also covers the levels after it. On its own, "caps" applies to the first
+// two levels and "unicode" to the first, leaving the others bold.
+func headingStyles(style string) [3]string {
+	switch style {
+	case "", "bold":
+		return [3]string{"bold", "bold", "bold"}
+	case "caps":
+		return [3]string{"caps", "caps", "bold"}
+	case "unicode":
+		return [3]string{"unicode", "bold", "bold"}
+	}
+
+	var styles [3]string
+	parts := strings.Split(style, ",")
+	for level := range styles {
+		styles[level] = strings.TrimSpace(parts[min(level, len(parts)-1)])
+	}
+	return styles
+}
+
+// Patterns for headings and the link targets inside them
+var (
+	headerRegex       = regexp.MustCompile(`(?m)^(#{1,3}) (.*)$`)
+	headerTargetRegex = regexp.MustCompile(`\]\([^)]*\)|\]\[[^\]]*\]`)
+)
+
+// convertHeaders converts headers to bold, or in the styles headingStyles
+// picks for each level: "plain" lines, upper-cased "caps" or "unicode" bold
+// underlined with ━, or with = when ascii is set. Bold is written as markdown
+// **bold** for the emphasis stage to convert along with the rest.
+func convertHeaders(text string, style string, ascii bool) string {
+	styles := headingStyles(style)
+
+	return headerRegex.ReplaceAllStringFunc(text, func(header string) string {
+		m := headerRegex.FindStringSubmatch(header)
+		level := styles[len(m[1])-1]
+		if level == "plain" {
+			return m[2]
+		}
+
+		// The whole heading is bold, so bold inside it adds nothing
+		title := strings.ReplaceAll(strings.TrimSpace(m[2]), "**", "")
+		switch level {
+		case "caps":
+			// Link targets keep their case
+			var b strings.Builder
+			last := 0
+			for _, loc := range headerTargetRegex.FindAllStringIndex(title, -1) {
+				b.WriteString(strings.ToUpper(title[last:loc[0]]))
+				b.WriteString(title[loc[0]:loc[1]])
+				last = loc[1]
+			}
+			b.WriteString(strings.ToUpper(title[last:]))
+			return "**" + b.String() + "**"
+		case "unicode":
+			rule := "━"
+			if ascii {
+				rule = "="
+			}
+			return "**" + title + "**\n" + strings.Repeat(rule, utf8.RuneCountInString(title))
+		}
+		return "**" + title + "**"
+	})
+}
+
+// listRule replaces the marker of unordered list items at one nesting level
+type listRule struct {
+	marker      *regexp.Regexp
+	replacement string
+}
+
+// listRules compiles the list marker rules for bullets, one per nesting level:
+// - becomes •, and a nested - becomes ◦ unless other bullets are given. Nested
+// items are reindented to width spaces per level when width is set.
+func listRules(bullets []string, width int) []listRule {
+	if len(bullets) == 0 {
+		bullets = []string{"•", "◦"}
+	}
+	if width <= 0 {
+		width = 2
+	}
+
+	rules := make([]listRule, len(bullets))
+	for level, bullet := range bullets {
+		indent := strings.Repeat("  ", level)
+		rules[level] = listRule{regexp.MustCompile(`(?m)^` + indent + `- `), strings.Repeat(" ", width*level) + bullet + " "}
+	}
+	return rules
+}
+
+// footnoteLinks replaces the links outside code with their text and a [n]
+// marker, listing the URLs by number at the end of text. Links to the same
+// URL share a number, and numbering carries on from any footnotes already
+// listed there. With preserve set the blank lines ending text are kept.
+func footnoteLinks(text string, preserve bool) string {
+	first := lastNote(text) + 1
+	var urls []string
+	text = mapOutsideFences(text, func(s string) string {
+		return linkRegex.ReplaceAllStringFunc(s, func(link string) string {
+			m := linkRegex.FindStringSubmatch(link)
+			n := slices.Index(urls, m[2])
+			if n < 0 {
+				urls = append(urls, m[2])
+				n = len(urls) - 1
+			}
+			return fmt.Sprintf("%s [%d]", m[1], first+n)
+		})
+	})
+	if len(urls) == 0 {
+		return text
+	}
+
+	notes := make([]string, len(urls))
+	for i, url := range urls {
+		notes[i] = fmt.Sprintf("[%d] %s", first+i, url)
+	}
+	if first > 1 {
+		return strings.TrimRight(text, "\n") + "\n" + strings.Join(notes, "\n") + "\n"
+	}
+	return appendSection(text, strings.Join(notes, "\n")+"\n", preserve)
+}
+
+// convertLists converts unordered list markers using rules
+func convertLists(text string, rules []listRule) string {
+	for _, rule := range rules {
+		text = rule.marker.ReplaceAllLiteralString(text, rule.replacement)
+	}
+	return text
+}
+
+// orderedItemRegex finds an ordered list item and its number
+var orderedItemRegex = regexp.MustCompile(`^( *)(\d+)[.)] `)
+
+// convertOrderedLists renumbers ordered list items the way markdown renders
+// them, counting up from each list's first number, so lists written as all
+// 1. come out numbered. style picks the marker, as in Options.OrderedLists.
+func convertOrderedLists(text string, style string, bullets []string) string {
+	if len(bullets) == 0 {
+		bullets = []string{"•", "◦"}
+	}
+
+	// The indents of the lists open around the current line, with their counters
+	var indents, counters []int
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		m := orderedItemRegex.FindStringSubmatch(line)
+		if m == nil {
+			// A paragraph at the margin ends every open list
+			if line != "" && line[0] != ' ' {
+				indents, counters = nil, nil
+			}
+			continue
+		}
+
+		indent := len(m[1])
+		for len(indents) > 0 && indents[len(indents)-1] > indent {
+			indents, counters = indents[:len(indents)-1], counters[:len(counters)-1]
+		}
+		if len(indents) > 0 && indents[len(indents)-1] == indent {
+			counters[len(counters)-1]++
+		} else {
+			start, _ := strconv.Atoi(m[2])
+			indents, counters = append(indents, indent), append(counters, start)
+		}
+
+		number := counters[len(counters)-1]
+		var marker string
+		switch style {
+		case "paren":
+			marker = fmt.Sprintf("%d)", number)
+		case "bold":
+			marker = fmt.Sprintf("*%d.*", number)
+		case "bullets":
+			marker = bullets[min(len(indents), len(bullets))-1]
+		default:
+			marker = fmt.Sprintf("%d.", number)
+		}
+		lines[i] = m[1] + marker + " " + line[len(m[0]):]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// slackControlRegex finds the characters Slack needs escaped, along with
+// the entities and markup that must be left alone
+var slackControlRegex = regexp.MustCompile(`(?m)&(?:amp|lt|gt);|<(?:https?://|mailto:|[@#!])[^<>\n]*>|^>|[&<>]`)
+
+// escapeSlack escapes the &, < and > characters Slack treats as control
+// characters. Existing entities, Slack's <url|text> links and <@mentions>,
+// and > quote markers at the start of a line are kept.
+func escapeSlack(text string) string {
+	return slackControlRegex.ReplaceAllStringFunc(text, func(match string) string {
+		switch match {
+		case "&":
+			return "&amp;"
+		case "<":
+			return "&lt;"
+		case ">":
+			return "&gt;"
+		}
+		return match
+	})
+}
+
+// ToMarkdown runs the front-end for an input format ("markdown", "asciidoc",
+// "html" or "pandoc-json"), producing markdown for Convert
+func ToMarkdown(text string, format string) (string, error) {
+	text = normalizeLineEndings(text)
+	switch format {
+	case "pandoc-json":
+		return pandocToMarkdown([]byte(text))
+	case "html":
+		return htmlToMarkdown(text)
+	case "asciidoc":
+		return asciidocToMarkdown(text)
+	}
+	return text, nil
+}
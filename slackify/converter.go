@@ -0,0 +1,245 @@
+package slackify
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+)
+
+// Converter converts markdown to Slack mrkdwn with a fixed set of options.
+// Its patterns are compiled once by New, and a Converter is safe for
+// concurrent use by multiple goroutines.
+type Converter struct {
+	opts   Options
+	stages []stage
+	// markdownStages counts the leading stages whose output still is
+	// markdown, which rich text output is parsed from
+	markdownStages int
+}
+
+// Option configures a Converter
+type Option func(*Options)
+
+// New returns a Converter configured by the given options
+func New(options ...Option) *Converter {
+	c := &Converter{}
+	for _, option := range options {
+		option(&c.opts)
+	}
+	c.opts.Bullets = append([]string(nil), c.opts.Bullets...)
+	c.opts.TableColumns = append([]string(nil), c.opts.TableColumns...)
+	c.opts.GFM = append([]string(nil), c.opts.GFM...)
+	c.opts.SecretPatterns = append([]string(nil), c.opts.SecretPatterns...)
+	c.opts.Glossary = maps.Clone(c.opts.Glossary)
+	c.opts.Usergroups = maps.Clone(c.opts.Usergroups)
+	c.opts.BlockedHosts = append([]string(nil), c.opts.BlockedHosts...)
+	c.opts.AllowedHosts = append([]string(nil), c.opts.AllowedHosts...)
+	c.opts.InlineSyntax = append([]InlineSyntax(nil), c.opts.InlineSyntax...)
+	c.opts.TicketLinks = append([]TicketLink(nil), c.opts.TicketLinks...)
+	c.stages = stages(c.opts)
+	c.markdownStages = slices.IndexFunc(c.stages, func(s stage) bool {
+		return s.name == "headers"
+	})
+	return c
+}
+
+// Options returns a copy of the converter's options
+func (c *Converter) Options() Options {
+	opts := c.opts
+	opts.Bullets = append([]string(nil), c.opts.Bullets...)
+	opts.TableColumns = append([]string(nil), c.opts.TableColumns...)
+	opts.GFM = append([]string(nil), c.opts.GFM...)
+	opts.SecretPatterns = append([]string(nil), c.opts.SecretPatterns...)
+	opts.Glossary = maps.Clone(c.opts.Glossary)
+	opts.Usergroups = maps.Clone(c.opts.Usergroups)
+	opts.BlockedHosts = append([]string(nil), c.opts.BlockedHosts...)
+	opts.AllowedHosts = append([]string(nil), c.opts.AllowedHosts...)
+	opts.InlineSyntax = append([]InlineSyntax(nil), c.opts.InlineSyntax...)
+	opts.TicketLinks = append([]TicketLink(nil), c.opts.TicketLinks...)
+	return opts
+}
+
+// Convert converts markdown text to Slack mrkdwn
+func (c *Converter) Convert(text string) string {
+	result, _ := runStages(context.Background(), text, c.stages, c.opts)
+	return result
+}
+
+// ConvertWithWarnings converts markdown text to Slack mrkdwn, returning the
+// warnings of Check and Secrets with it in order of their position in text
+func (c *Converter) ConvertWithWarnings(text string) (string, []Warning, error) {
+	result, err := runStages(context.Background(), text, c.stages, c.opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	warnings := append(c.Check(text), c.Secrets(text)...)
+	slices.SortStableFunc(warnings, func(a, b Warning) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return result, warnings, nil
+}
+
+// ConvertContext reads markdown from r and writes Slack mrkdwn to w, stopping
+// with the context's error once ctx is done
+func (c *Converter) ConvertContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	result, err := runStages(ctx, string(data), c.stages, c.opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, result)
+	return err
+}
+
+// Check reports the constructs in markdown text that Convert drops or cannot
+// represent, with their position in text
+func (c *Converter) Check(text string) []Warning {
+	return Check(text, c.opts)
+}
+
+// Fidelity measures how faithfully markdown text converts
+func (c *Converter) Fidelity(text string) Fidelity {
+	return CheckFidelity(text, c.opts)
+}
+
+// Secrets reports the credentials in markdown text, with their position
+func (c *Converter) Secrets(text string) []Warning {
+	return Secrets(text, c.opts)
+}
+
+// WithOptions replaces all options with opts; later options still apply on top
+func WithOptions(opts Options) Option {
+	return func(o *Options) {
+		*o = opts
+	}
+}
+
+// WithLinkStyle renders links "inline" as text (url), "slack" as <url|text>,
+// "text" without the URL or "footnotes" as text [n] with the URLs at the end
+func WithLinkStyle(style string) Option {
+	return func(o *Options) {
+		o.LinkStyle = style
+	}
+}
+
+// WithBullets sets the unordered list markers, one per nesting level
+func WithBullets(bullets ...string) Option {
+	return func(o *Options) {
+		o.Bullets = bullets
+	}
+}
+
+// WithOrderedLists renders ordered list markers as "number", "paren", "bold"
+// or "bullets"
+func WithOrderedLists(style string) Option {
+	return func(o *Options) {
+		o.OrderedLists = style
+	}
+}
+
+// WithTableColumns shows only the named table columns, in order; see
+// Options.TableColumns
+func WithTableColumns(columns ...string) Option {
+	return func(o *Options) {
+		o.TableColumns = columns
+	}
+}
+
+// WithIndent sets the number of spaces per list nesting level
+func WithIndent(width int) Option {
+	return func(o *Options) {
+		o.Indent = width
+	}
+}
+
+// WithTableMode renders tables as a "code" block, "plain" aligned text or
+// leaves them "raw"
+func WithTableMode(mode string) Option {
+	return func(o *Options) {
+		o.TableMode = mode
+	}
+}
+
+// WithHeadingStyle renders headings "bold", "plain", "caps" or "unicode", or
+// in a comma-separated style per level
+func WithHeadingStyle(style string) Option {
+	return func(o *Options) {
+		o.HeadingStyle = style
+	}
+}
+
+// WithEscaping turns escaping of Slack control characters on or off
+func WithEscaping(enabled bool) Option {
+	return func(o *Options) {
+		o.Escape = enabled
+	}
+}
+
+// WithRedaction masks credentials, including those matching patterns
+func WithRedaction(patterns ...string) Option {
+	return func(o *Options) {
+		o.Redact = true
+		o.SecretPatterns = patterns
+	}
+}
+
+// WithGlossary replaces terms with their glossary entries after conversion
+func WithGlossary(glossary map[string]string) Option {
+	return func(o *Options) {
+		o.Glossary = glossary
+	}
+}
+
+// WithBlockedHosts removes links to the given hosts, keeping their text
+func WithBlockedHosts(hosts ...string) Option {
+	return func(o *Options) {
+		o.BlockedHosts = hosts
+	}
+}
+
+// WithAllowedHosts removes links to any host but the given ones
+func WithAllowedHosts(hosts ...string) Option {
+	return func(o *Options) {
+		o.AllowedHosts = hosts
+	}
+}
+
+// WithShortener shortens URLs longer than over characters with shortener
+func WithShortener(shortener Shortener, over int) Option {
+	return func(o *Options) {
+		o.Shortener = shortener
+		o.ShortenOver = over
+	}
+}
+
+// WithFooter ends each message of split output with footer, a line of mrkdwn
+func WithFooter(footer string) Option {
+	return func(o *Options) {
+		o.Footer = footer
+	}
+}
+
+// WithInlineSyntax adds custom inline markup: text matching pattern outside
+// code is replaced by the mrkdwn render returns for the match and its
+// submatches, and left alone by the rest of the conversion
+func WithInlineSyntax(pattern *regexp.Regexp, render func(match []string) string) Option {
+	return func(o *Options) {
+		o.InlineSyntax = append(o.InlineSyntax, InlineSyntax{pattern, render})
+	}
+}
+
+// WithTrace logs what each conversion stage changed to w
+func WithTrace(w io.Writer) Option {
+	return func(o *Options) {
+		o.Trace = w
+	}
+}
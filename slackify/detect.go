@@ -0,0 +1,68 @@
+package slackify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// formatSignals are patterns that hint at an input format, with their weight
+var formatSignals = map[string][]struct {
+	pattern *regexp.Regexp
+	weight  int
+}{
+	"markdown": {
+		{regexp.MustCompile(`\A---\n(?:.*\n)*?---\n`), 5}, // YAML frontmatter
+		{regexp.MustCompile(`(?m)^#{1,6} \S`), 2},
+		{regexp.MustCompile(`\*\*[^*\n]+\*\*`), 1},
+		{regexp.MustCompile(`\[[^\]\n]+\]\([^)\n]+\)`), 2},
+		{regexp.MustCompile("(?m)^```"), 1},
+		{regexp.MustCompile(`(?m)^\|.*\|\s*$`), 1},
+	},
+	"asciidoc": {
+		{regexp.MustCompile(`(?m)^={2,6} \S`), 2},
+		{regexp.MustCompile(`(?m)^:[\w-]+:`), 2},
+		{regexp.MustCompile(`(?m)^\|===\s*$`), 3},
+		{regexp.MustCompile(`(?m)^\[source(,[^\]]*)?\]$`), 3},
+		{regexp.MustCompile(`(?:link:|https?://)[^\s\[]+\[[^\]\n]*\]`), 2},
+		{regexp.MustCompile(`(?m)^(NOTE|TIP|IMPORTANT|WARNING|CAUTION): `), 2},
+	},
+	"html": {
+		{regexp.MustCompile(`(?i)<(p|div|br|table|tr|td|ul|ol|li|h[1-6]|span|strong|em|a)(\s[^>]*)?/?>`), 1},
+	},
+}
+
+// Patterns that settle the format on their own: an HTML document starts with
+// a doctype or an <html> root, an AsciiDoc document with a = title line
+var (
+	htmlDocumentRegex  = regexp.MustCompile(`(?i)\A\s*(?:<!DOCTYPE html|<html[\s>])`)
+	asciidocTitleRegex = regexp.MustCompile(`\A\s*= \S`)
+)
+
+// DetectFormat guesses the format of an input document from its content.
+// Only unambiguous evidence picks another format than markdown: pandoc JSON,
+// an HTML doctype or <html> root, or an AsciiDoc document title. Slack mrkdwn
+// is never picked, since markdown may already contain Slack links and
+// mentions. The guess is ambiguous when the text has no markdown syntax but
+// does have HTML or AsciiDoc markup.
+func DetectFormat(text string) (format string, ambiguous bool) {
+	text = normalizeLineEndings(text)
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(trimmed, "{") && strings.Contains(trimmed, `"pandoc-api-version"`):
+		return "pandoc-json", false
+	case htmlDocumentRegex.MatchString(text):
+		return "html", false
+	case asciidocTitleRegex.MatchString(text):
+		return "asciidoc", false
+	}
+
+	scores := map[string]int{}
+	for name, signals := range formatSignals {
+		for _, signal := range signals {
+			// Cap each signal so one repetitive pattern cannot dominate
+			matches := len(signal.pattern.FindAllStringIndex(text, 10))
+			scores[name] += matches * signal.weight
+		}
+	}
+	return "markdown", scores["markdown"] == 0 && (scores["html"] > 0 || scores["asciidoc"] > 0)
+}
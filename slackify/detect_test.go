@@ -0,0 +1,33 @@
+package slackify
+
+import "testing"
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name, in  string
+		format    string
+		ambiguous bool
+	}{
+		{"markdown", "# Title\n\nSome **bold** text", "markdown", false},
+		{"plain text", "Just a line", "markdown", false},
+		{"slack mention", "Ping <@U12345> **now**", "markdown", false},
+		{"slack link", "See <https://example.com|the docs>", "markdown", false},
+		{"bullets and broadcast", "<!here>\n• one\n• two", "markdown", false},
+		{"readme with html badges", "<p align=\"center\"><img src=\"logo.png\"></p>\n\n## Install\n\n**go get** it", "markdown", false},
+		{"inline html", "Press <kbd>Ctrl</kbd> or see <a href=\"x\">this</a>", "markdown", true},
+		{"html fragment", "<p>Hello <strong>world</strong></p>", "markdown", true},
+		{"html doctype", "<!DOCTYPE html>\n<html><body><p>Hi</p></body></html>", "html", false},
+		{"html root", "  <html lang=\"en\"><p>Hi</p></html>", "html", false},
+		{"asciidoc title", "= Guide\n\n== Install\n\nText", "asciidoc", false},
+		{"asciidoc markup", "== Install\n\nNOTE: read this", "markdown", true},
+		{"pandoc json", `{"pandoc-api-version":[1,23],"meta":{},"blocks":[]}`, "pandoc-json", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ambiguous := DetectFormat(tt.in)
+			if format != tt.format || ambiguous != tt.ambiguous {
+				t.Errorf("DetectFormat(%q) = %q, %v, want %q, %v", tt.in, format, ambiguous, tt.format, tt.ambiguous)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package slackify
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// delimiterRun is a run of * or _ characters that may open or close
+// emphasis. count is what is left of the run once matched characters are
+// used up; open and close collect the Slack markup that replaces them.
+type delimiterRun struct {
+	char              byte
+	length, count     int
+	canOpen, canClose bool
+	open, close       string
+}
+
+// convertEmphasis converts markdown emphasis to Slack's: **bold** and
+// __bold__ become *bold*, and *italic* and _italic_ become _italic_. Runs of
+// * and _ are matched the way CommonMark matches them, one line at a time,
+// so list markers, lone asterisks and snake_case words stay as written.
+// Code spans, link destinations and autolinks are left alone.
+func convertEmphasis(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.ContainsAny(line, "*_") {
+			lines[i] = emphasizeLine(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emphasizeLine splits line into literal text and delimiter runs, matches
+// the runs and writes the line back out with Slack markup
+func emphasizeLine(line string) string {
+	type piece struct {
+		text string
+		run  *delimiterRun
+	}
+	var pieces []piece
+	var runs []*delimiterRun
+
+	literal := 0
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i += 2
+		case c == '`':
+			n := runLength(line, i)
+			fence := strings.Repeat("`", n)
+			if end := strings.Index(line[i+n:], fence); end >= 0 {
+				i += n + end + n
+			} else {
+				i += n
+			}
+		case strings.HasPrefix(line[i:], "]("):
+			if end := strings.IndexByte(line[i:], ')'); end >= 0 {
+				i += end + 1
+			} else {
+				i++
+			}
+		case strings.HasPrefix(line[i:], "<http") || strings.HasPrefix(line[i:], "<mailto:"):
+			if end := strings.IndexByte(line[i:], '>'); end >= 0 {
+				i += end + 1
+			} else {
+				i++
+			}
+		case c == '*' || c == '_':
+			n := runLength(line, i)
+			run := &delimiterRun{char: c, length: n, count: n}
+			run.canOpen, run.canClose = delimiterSides(line, i, i+n, c)
+			if literal < i {
+				pieces = append(pieces, piece{text: line[literal:i]})
+			}
+			pieces = append(pieces, piece{run: run})
+			runs = append(runs, run)
+			i += n
+			literal = i
+		default:
+			i++
+		}
+	}
+	if len(runs) == 0 {
+		return line
+	}
+	if literal < len(line) {
+		pieces = append(pieces, piece{text: line[literal:]})
+	}
+
+	matchDelimiters(runs)
+
+	var b strings.Builder
+	for _, p := range pieces {
+		if p.run == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		b.WriteString(p.run.close)
+		b.WriteString(strings.Repeat(string(p.run.char), p.run.count))
+		b.WriteString(p.run.open)
+	}
+	return b.String()
+}
+
+// runLength returns the length of the run of line[i] characters at i
+func runLength(line string, i int) int {
+	n := 1
+	for i+n < len(line) && line[i+n] == line[i] {
+		n++
+	}
+	return n
+}
+
+// delimiterSides reports whether the run of c at line[start:end] can open
+// and close emphasis, by CommonMark's flanking rules. Underscores inside a
+// word do neither.
+func delimiterSides(line string, start, end int, c byte) (canOpen, canClose bool) {
+	before, after := ' ', ' '
+	if start > 0 {
+		before, _ = utf8.DecodeLastRuneInString(line[:start])
+	}
+	if end < len(line) {
+		after, _ = utf8.DecodeRuneInString(line[end:])
+	}
+	isPunct := func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}
+
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+	if c == '*' {
+		return left, right
+	}
+	return left && (!right || isPunct(before)), right && (!left || isPunct(after))
+}
+
+// matchDelimiters pairs closing runs with the nearest opening run of the
+// same character, using two characters for bold where both runs have them
+// and one for italics. Runs between a matched pair stay literal.
+func matchDelimiters(runs []*delimiterRun) {
+	for c, closer := range runs {
+		for closer.canClose && closer.count > 0 {
+			o := c - 1
+			for ; o >= 0; o-- {
+				opener := runs[o]
+				if opener.char != closer.char || !opener.canOpen || opener.count == 0 {
+					continue
+				}
+				// CommonMark's rule of three keeps *a**b* from pairing the wrong runs
+				if (opener.canClose || closer.canOpen) && (opener.length+closer.length)%3 == 0 &&
+					(opener.length%3 != 0 || closer.length%3 != 0) {
+					continue
+				}
+				break
+			}
+			if o < 0 {
+				break
+			}
+
+			opener := runs[o]
+			use, marker := 1, "_"
+			if opener.count >= 2 && closer.count >= 2 {
+				use, marker = 2, "*"
+			}
+			opener.count -= use
+			closer.count -= use
+			opener.open = marker + opener.open
+			closer.close += marker
+			for _, between := range runs[o+1 : c] {
+				between.canOpen, between.canClose = false, false
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package slackify
+
+import "testing"
+
+func TestConvertEmphasis(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"bold", "**bold**", "*bold*"},
+		{"bold underscores", "__bold__", "*bold*"},
+		{"italic", "*italic*", "_italic_"},
+		{"italic underscores", "_italic_", "_italic_"},
+		{"bold italic", "***both***", "_*both*_"},
+		{"bold in italic", "*a **b** c*", "_a *b* c_"},
+		{"italic in bold", "**a *b* c**", "*a _b_ c*"},
+
+		// Left- and right-flanking runs
+		{"space after opener", "** not bold**", "** not bold**"},
+		{"space before closer", "**not bold **", "**not bold **"},
+		{"lone asterisk", "2 * 3 * 4", "2 * 3 * 4"},
+		{"punctuation inside", "**\"quoted\"**", "*\"quoted\"*"},
+		{"punctuation outside", "a**\"b\"**", "a**\"b\"**"},
+		{"intraword asterisks", "un*frigging*believable", "un_frigging_believable"},
+
+		// Intraword underscores
+		{"snake case", "snake_case_name", "snake_case_name"},
+		{"intraword underscores", "foo__bar__baz", "foo__bar__baz"},
+		{"underscore before word", "_foo_bar", "_foo_bar"},
+		{"underscore after punctuation", "(_italic_)", "(_italic_)"},
+
+		// The rule of three
+		{"rule of three", "*a**b*", "_a**b_"},
+		{"bold after italic", "*foo**bar**baz*", "_foo*bar*baz_"},
+		{"both multiples of three", "***a***b", "_*a*_b"},
+
+		// Code spans and links
+		{"code span", "`**not bold**`", "`**not bold**`"},
+		{"double backtick span", "``a ` **b**``", "``a ` **b**``"},
+		{"unclosed backtick", "` **bold**", "` *bold*"},
+		{"escaped", `\*not italic\*`, `\*not italic\*`},
+		{"link text", "[**bold**](https://example.com)", "[*bold*](https://example.com)"},
+		{"link destination", "[x](https://example.com/__init__)", "[x](https://example.com/__init__)"},
+		{"autolink", "<https://example.com/*a*>", "<https://example.com/*a*>"},
+
+		// Lines are matched one at a time
+		{"across lines", "*a\nb*", "*a\nb*"},
+		{"list marker", "* item with *italic*", "* item with _italic_"},
+		{"placeholder text", "BOLD_TEMP0_TEMP", "BOLD_TEMP0_TEMP"},
+		{"regex characters", "**a+b (c)**", "*a+b (c)*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertEmphasis(tt.in); got != tt.want {
+				t.Errorf("convertEmphasis(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelimiterSides(t *testing.T) {
+	tests := []struct {
+		line              string
+		start, end        int
+		canOpen, canClose bool
+	}{
+		{"**a", 0, 2, true, false},
+		{"a**", 1, 3, false, true},
+		{"a**b", 1, 3, true, true},
+		{"a__b", 1, 3, false, false},
+		{"a __b", 2, 4, true, false},
+		{"a__ b", 1, 3, false, true},
+		{"(__a", 1, 3, true, false},
+		{"a__)", 1, 3, false, true},
+		{"** a", 0, 2, false, false},
+	}
+	for _, tt := range tests {
+		canOpen, canClose := delimiterSides(tt.line, tt.start, tt.end, tt.line[tt.start])
+		if canOpen != tt.canOpen || canClose != tt.canClose {
+			t.Errorf("delimiterSides(%q, %d, %d) = %v, %v, want %v, %v", tt.line, tt.start, tt.end, canOpen, canClose, tt.canOpen, tt.canClose)
+		}
+	}
+}
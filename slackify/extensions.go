@@ -0,0 +1,515 @@
+package slackify
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dashRegex finds a lone pair of dashes between two words or two spaces,
+// which is an em-dash; longer runs are rules or table separators, and a pair
+// starting a word is a command-line flag like --help
+var dashRegex = regexp.MustCompile(`\w--\w| -- `)
+
+// normalizePunctuation rewrites quotes, dashes and ellipses outside code,
+// either down to plain ASCII or up to their typographic Unicode forms
+func normalizePunctuation(text string, style string) string {
+	switch style {
+	case "ascii":
+		replacer := strings.NewReplacer(
+			"\u201c", "\"", "\u201d", "\"", "\u201e", "\"", "\u201f", "\"",
+			"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'",
+			"\u2026", "...",
+			"\u2014", "--",
+			"\u2013", "-",
+		)
+		return mapOutsideCode(text, replacer.Replace)
+	case "unicode":
+		return mapOutsideCode(text, func(s string) string {
+			s = strings.ReplaceAll(s, "...", "\u2026")
+			s = dashRegex.ReplaceAllStringFunc(s, func(dash string) string {
+				return strings.Replace(dash, "--", "\u2014", 1)
+			})
+			return smartQuotes(s)
+		})
+	}
+	return text
+}
+
+// smartQuotes turns straight quotes into curly ones, leaving HTML tags untouched
+func smartQuotes(text string) string {
+	var b strings.Builder
+	inTag := false
+	prev := ' '
+	runes := []rune(text)
+
+	for i, r := range runes {
+		switch {
+		case inTag:
+			if r == '>' {
+				inTag = false
+			}
+		case r == '<' && i+1 < len(runes) && (runes[i+1] == '/' || unicode.IsLetter(runes[i+1])):
+			inTag = true
+		case r == '"' || r == '\'':
+			// A quote is opening unless it follows a word or closing punctuation
+			opening := unicode.IsSpace(prev) || strings.ContainsRune("([{\u2014-", prev)
+			switch {
+			case r == '"' && opening:
+				r = '\u201c'
+			case r == '"':
+				r = '\u201d'
+			case opening:
+				r = '\u2018'
+			default:
+				r = '\u2019'
+			}
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+
+	return b.String()
+}
+
+// Patterns for <details> blocks and ||inline|| spoilers, and the punctuation
+// that closes a sentence or clause after one
+var (
+	detailsRegex            = regexp.MustCompile(`(?s)<details>\s*(?:<summary>(.*?)</summary>)?(.*?)</details>`)
+	inlineSpoilerRegex      = regexp.MustCompile(`\|\|(\S[^|\n]*?\S|\S)\|\|`)
+	closingPunctuationRegex = regexp.MustCompile(`^[.,;:!?…)\]}"'”’»]+`)
+)
+
+// convertSpoilers renders Discord-style ||spoilers|| and HTML <details>
+// blocks. "quote" sets them apart in a labelled quote block, "plain" keeps
+// just the content and "hide" replaces them with the label alone. Quoted
+// lines keep their trailing whitespace when preserve is set.
+func convertSpoilers(text string, style string, label string, preserve bool) string {
+	if label == "" {
+		label = ":no_entry_sign: *Spoiler*"
+	}
+
+	render := func(title, content string) string {
+		if title != "" {
+			title = label + ": " + title
+		} else {
+			title = label
+		}
+		switch style {
+		case "plain":
+			return content
+		case "hide":
+			return title
+		}
+		quoted := []string{"> " + title}
+		for _, line := range strings.Split(content, "\n") {
+			line = "> " + line
+			if !preserve || strings.TrimSpace(line) == ">" {
+				line = strings.TrimRight(line, " ")
+			}
+			quoted = append(quoted, line)
+		}
+		return strings.Join(quoted, "\n")
+	}
+
+	return mapOutsideCode(text, func(s string) string {
+		s = detailsRegex.ReplaceAllStringFunc(s, func(m string) string {
+			parts := detailsRegex.FindStringSubmatch(m)
+			return render(strings.TrimSpace(parts[1]), strings.TrimSpace(parts[2]))
+		})
+		if style != "quote" {
+			return inlineSpoilerRegex.ReplaceAllStringFunc(s, func(m string) string {
+				return render("", strings.TrimSpace(m[2:len(m)-2]))
+			})
+		}
+
+		// A quote must sit on its own line, so break the surrounding text
+		var b strings.Builder
+		last := 0
+		for _, loc := range inlineSpoilerRegex.FindAllStringSubmatchIndex(s, -1) {
+			before := strings.TrimRight(s[last:loc[0]], " ")
+			b.WriteString(before)
+			if before != "" && !strings.HasSuffix(before, "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("> " + label + ": " + strings.TrimSpace(s[loc[2]:loc[3]]))
+			last = loc[1]
+			// Punctuation right after the spoiler stays on its line
+			if n := len(closingPunctuationRegex.FindString(s[last:])); n > 0 {
+				b.WriteString(s[last : last+n])
+				last += n
+			}
+			if rest := strings.TrimLeft(s[last:], " "); rest != "" && !strings.HasPrefix(rest, "\n") {
+				b.WriteString("\n")
+				last = len(s) - len(rest)
+			}
+		}
+		b.WriteString(s[last:])
+		return b.String()
+	})
+}
+
+// superscripts and subscripts map characters to their Unicode script forms
+var (
+	superscripts = map[rune]rune{
+		'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴',
+		'5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+		'+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽', ')': '⁾',
+		'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ',
+		'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ', 'j': 'ʲ',
+		'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ',
+		'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ', 't': 'ᵗ', 'u': 'ᵘ',
+		'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ',
+	}
+	subscripts = map[rune]rune{
+		'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄',
+		'5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+		'+': '₊', '-': '₋', '=': '₌', '(': '₍', ')': '₎',
+		'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ',
+		'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ', 'o': 'ₒ',
+		'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ',
+		'v': 'ᵥ', 'x': 'ₓ',
+	}
+)
+
+// Patterns for superscript and subscript markup
+var (
+	superscriptRegex = regexp.MustCompile(`(?i)<sup>(.*?)</sup>|\^([^\s^\[\]]+)\^`)
+	subscriptRegex   = regexp.MustCompile(`(?i)<sub>(.*?)</sub>|(^|[^~])~([^\s~]+)~([^~]|$)`)
+)
+
+// convertScripts converts superscript and subscript markup to Unicode
+// characters. Text with characters that have no script form, or any text
+// when ascii is set, falls back to ^(text) for superscripts and plain text
+// for subscripts.
+func convertScripts(text string, ascii bool) string {
+	toScript := func(s string, table map[rune]rune) (string, bool) {
+		if ascii {
+			return s, false
+		}
+		var b strings.Builder
+		for _, r := range s {
+			mapped, ok := table[r]
+			if !ok {
+				return s, false
+			}
+			b.WriteRune(mapped)
+		}
+		return b.String(), true
+	}
+
+	text = superscriptRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := superscriptRegex.FindStringSubmatch(m)
+		content := parts[1] + parts[2]
+		if script, ok := toScript(content, superscripts); ok {
+			return script
+		}
+		if utf8.RuneCountInString(content) == 1 {
+			return "^" + content
+		}
+		return "^(" + content + ")"
+	})
+
+	return subscriptRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := subscriptRegex.FindStringSubmatch(m)
+		if parts[1] != "" {
+			script, _ := toScript(parts[1], subscripts)
+			return script
+		}
+		script, _ := toScript(parts[3], subscripts)
+		return parts[2] + script + parts[4]
+	})
+}
+
+// abbreviationRegex finds *[ABBR]: definition lines
+var abbreviationRegex = regexp.MustCompile(`(?m)^\*\[([^\]]+)\]:[ \t]*(.*)\n?`)
+
+// convertAbbreviations removes *[ABBR]: definition lines and either expands
+// each abbreviation at its first use outside code or lists them all in a
+// glossary appended to the document. With preserve set the blank lines
+// ending the document are kept.
+func convertAbbreviations(text string, mode string, preserve bool) string {
+	type abbreviation struct{ term, definition string }
+	abbreviations := []abbreviation{}
+	for _, m := range abbreviationRegex.FindAllStringSubmatch(text, -1) {
+		abbreviations = append(abbreviations, abbreviation{m[1], strings.TrimSpace(m[2])})
+	}
+	if len(abbreviations) == 0 {
+		return text
+	}
+	text = abbreviationRegex.ReplaceAllString(text, "")
+	if !preserve {
+		text = strings.TrimRight(text, "\n")
+	}
+
+	if mode == "glossary" {
+		glossary := []string{"**Abbreviations**", ""}
+		for _, a := range abbreviations {
+			glossary = append(glossary, fmt.Sprintf("- %s: %s", a.term, a.definition))
+		}
+		return appendSection(text, strings.Join(glossary, "\n"), preserve)
+	}
+
+	expanded := map[string]bool{}
+	return mapOutsideCode(text, func(s string) string {
+		for _, a := range abbreviations {
+			if expanded[a.term] {
+				continue
+			}
+			if i := termIndex(s, a.term); i >= 0 {
+				s = s[:i] + a.term + " (" + a.definition + ")" + s[i+len(a.term):]
+				expanded[a.term] = true
+			}
+		}
+		return s
+	})
+}
+
+// termIndex returns the offset of the first occurrence of term in s that is
+// not part of a longer word, or -1
+func termIndex(s, term string) int {
+	isWord := func(c byte) bool { return isWordByte(c) || c == '_' }
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], term)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if end := i + len(term); (i == 0 || !isWord(s[i-1])) && (end == len(s) || !isWord(s[end])) {
+			return i
+		}
+		offset = i + 1
+	}
+}
+
+// Patterns for each kind of CriticMarkup edit
+var (
+	criticAdditionRegex     = regexp.MustCompile(`(?s)\{\+\+(.*?)\+\+\}`)
+	criticDeletionRegex     = regexp.MustCompile(`(?s)\{--(.*?)--\}`)
+	criticSubstitutionRegex = regexp.MustCompile(`(?s)\{~~(.*?)~>(.*?)~~\}`)
+	criticHighlightRegex    = regexp.MustCompile(`(?s)\{==(.*?)==\}`)
+	criticCommentRegex      = regexp.MustCompile(`(?s)[ \t]*\{>>(.*?)<<\}`)
+)
+
+// convertCriticMarkup resolves CriticMarkup annotations. "accept" applies
+// every change, "reject" discards them and "show" keeps both sides visible
+// with deletions struck through and additions in bold.
+func convertCriticMarkup(text string, mode string) string {
+	switch mode {
+	case "accept":
+		text = criticAdditionRegex.ReplaceAllString(text, "$1")
+		text = removeCritic(text, criticDeletionRegex)
+		text = criticSubstitutionRegex.ReplaceAllString(text, "$2")
+	case "reject":
+		text = removeCritic(text, criticAdditionRegex)
+		text = criticDeletionRegex.ReplaceAllString(text, "$1")
+		text = criticSubstitutionRegex.ReplaceAllString(text, "$1")
+	case "show":
+		// Markers must hug the text, so surrounding spaces move outside them
+		wrap := func(re *regexp.Regexp, open, close string) {
+			text = re.ReplaceAllStringFunc(text, func(m string) string {
+				content := re.FindStringSubmatch(m)[1]
+				trimmed := strings.TrimSpace(content)
+				if trimmed == "" {
+					return content
+				}
+				i := strings.Index(content, trimmed)
+				return content[:i] + open + trimmed + close + content[i+len(trimmed):]
+			})
+		}
+		wrap(criticAdditionRegex, "**", "**")
+		wrap(criticDeletionRegex, "~", "~")
+		wrap(criticHighlightRegex, "**", "**")
+		text = criticSubstitutionRegex.ReplaceAllString(text, "~$1~ **$2**")
+		return criticCommentRegex.ReplaceAllString(text, " _($1)_")
+	}
+
+	text = criticHighlightRegex.ReplaceAllString(text, "$1")
+	return criticCommentRegex.ReplaceAllString(text, "")
+}
+
+// removeCritic deletes the edits re matches in text along with a space next
+// to them, so dropping the edit from "a {--b--} c" leaves "a c", not "a  c"
+func removeCritic(text string, re *regexp.Regexp) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		before, after := text[last:loc[0]], text[loc[1]:]
+		switch {
+		case strings.HasSuffix(before, " ") && (after == "" || after[0] == ' ' || after[0] == '\n' || closingPunctuationRegex.MatchString(after)):
+			before = before[:len(before)-1]
+		case (loc[0] == 0 || text[loc[0]-1] == '\n') && strings.HasPrefix(after, " "):
+			loc[1]++
+		}
+		b.WriteString(before)
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// highlightRegex finds ==highlighted== text, which must not start or end
+// with a space so comparisons like a == b == c are left alone
+var highlightRegex = regexp.MustCompile(`(^|.)==([^\s=](?:[^\n=]*[^\s=])?)==`)
+
+// convertHighlights renders ==highlighted== text as style says: "bold" or
+// "", "plain" for the text alone, or an :emoji: code placed on both sides.
+// CriticMarkup's {==highlights==} are left to the critic markup stage.
+func convertHighlights(text string, style string) string {
+	return highlightRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := highlightRegex.FindStringSubmatch(match)
+		if m[1] == "{" {
+			return match
+		}
+		switch style {
+		case "", "bold":
+			return m[1] + "**" + m[2] + "**"
+		case "plain":
+			return m[1] + m[2]
+		default:
+			return m[1] + style + " " + m[2] + " " + style
+		}
+	})
+}
+
+// inlineNoteRegex finds Pandoc ^[inline footnotes], which may hold one level
+// of brackets, like a link
+var inlineNoteRegex = regexp.MustCompile(`[ \t]*\^\[((?:[^\[\]\n]|\[[^\[\]\n]*\])+)\]`)
+
+// convertInlineNotes turns ^[inline footnotes] into " (note)" parentheticals,
+// or with notes set into footnote references with definitions at the end
+// of text, for the footnotes stage to number along with the others. With
+// preserve set the blank lines ending text are kept.
+func convertInlineNotes(text string, notes bool, preserve bool) string {
+	var definitions []string
+	text = mapOutsideCode(text, func(s string) string {
+		return inlineNoteRegex.ReplaceAllStringFunc(s, func(note string) string {
+			content := strings.TrimSpace(inlineNoteRegex.FindStringSubmatch(note)[1])
+			if !notes {
+				return " (" + content + ")"
+			}
+			// A label no footnote written by hand can have
+			label := fmt.Sprintf("\x00%d", len(definitions)+1)
+			definitions = append(definitions, "[^"+label+"]: "+content)
+			return "[^" + label + "]"
+		})
+	})
+	if len(definitions) == 0 {
+		return text
+	}
+	return appendSection(text, strings.Join(definitions, "\n")+"\n", preserve)
+}
+
+// calloutEmoji maps Obsidian callout and GitHub alert types to emoji
+var calloutEmoji = map[string]string{
+	"note":      ":memo:",
+	"abstract":  ":clipboard:",
+	"summary":   ":clipboard:",
+	"tldr":      ":clipboard:",
+	"info":      ":information_source:",
+	"todo":      ":ballot_box_with_check:",
+	"tip":       ":bulb:",
+	"hint":      ":bulb:",
+	"important": ":exclamation:",
+	"success":   ":white_check_mark:",
+	"check":     ":white_check_mark:",
+	"done":      ":white_check_mark:",
+	"question":  ":question:",
+	"help":      ":question:",
+	"faq":       ":question:",
+	"warning":   ":warning:",
+	"caution":   ":warning:",
+	"attention": ":warning:",
+	"failure":   ":x:",
+	"fail":      ":x:",
+	"missing":   ":x:",
+	"danger":    ":zap:",
+	"error":     ":zap:",
+	"bug":       ":beetle:",
+	"example":   ":page_facing_up:",
+	"quote":     ":speech_balloon:",
+	"cite":      ":speech_balloon:",
+}
+
+// Patterns for the first line of a callout or alert, and the bold spans its
+// title may already have
+var (
+	calloutRegex  = regexp.MustCompile(`(?m)^>[ \t]*\[!(\w+)\][+-]?[ \t]*(.*)$`)
+	boldSpanRegex = regexp.MustCompile(`\*+([^*\n]+)\*+`)
+)
+
+// indentBlockquotes indents the lines of quotes, the way Slack messages set
+// them off without a quote bar. Callouts keep their > lines, from the
+// [!type] line to the end of the quote, so they render as real quotes.
+func indentBlockquotes(text string) string {
+	lines := strings.Split(text, "\n")
+	callout := false
+	for i, line := range lines {
+		if !strings.HasPrefix(line, ">") {
+			callout = false
+			continue
+		}
+		if calloutRegex.MatchString(line) {
+			callout = true
+		}
+		if !callout {
+			lines[i] = blockquoteRegex.ReplaceAllString(line, "    ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertCallouts turns the first line of an Obsidian callout or GitHub alert
+// (> [!type] Title) into an emoji and a title wrapped in bold, like the label
+// of a quoted spoiler: * after the markdown rewrites, ** before rich text
+// blocks are built. With ascii set the emoji is left out.
+func convertCallouts(text string, ascii bool, bold string) string {
+	return calloutRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := calloutRegex.FindStringSubmatch(m)
+		kind := strings.ToLower(parts[1])
+		title := boldSpanRegex.ReplaceAllString(strings.TrimSpace(parts[2]), "$1")
+		if title == "" {
+			title = strings.ToUpper(kind[:1]) + kind[1:]
+		}
+		if ascii {
+			return "> " + bold + title + bold
+		}
+		emoji, ok := calloutEmoji[kind]
+		if !ok {
+			emoji = ":memo:"
+		}
+		return "> " + emoji + " " + bold + title + bold
+	})
+}
+
+// Patterns for wiki links and the page slugs made from them
+var (
+	wikiLinkRegex = regexp.MustCompile(`!?\[\[([^\]|\n]+)(?:\|([^\]\n]+))?\]\]`)
+	slugRegex     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// convertWikiLinks converts Obsidian-style [[Page]], [[Page#Heading]] and
+// [[Page|alias]] links (and ![[embeds]]). With a URL template they become
+// markdown links, rendered by the links stage in the chosen --links style;
+// otherwise only their text is kept.
+func convertWikiLinks(text string, urlTemplate string) string {
+	return wikiLinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := wikiLinkRegex.FindStringSubmatch(m)
+		page := strings.TrimSpace(parts[1])
+		label := strings.TrimSpace(parts[2])
+		if label == "" {
+			label = strings.Replace(page, "#", " > ", 1)
+		}
+		if urlTemplate == "" {
+			return label
+		}
+
+		page, _, _ = strings.Cut(page, "#")
+		slug := strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(page), "-"), "-")
+		link := strings.NewReplacer("{page}", url.PathEscape(page), "{slug}", slug).Replace(urlTemplate)
+		return fmt.Sprintf("[%s](%s)", label, link)
+	})
+}
@@ -0,0 +1,112 @@
+package slackify
+
+import "testing"
+
+func TestTermIndex(t *testing.T) {
+	tests := []struct {
+		s, term string
+		want    int
+	}{
+		{"HTML spec", "HTML", 0},
+		{"the HTML", "HTML", 4},
+		{"HTMLX and HTML", "HTML", 10},
+		{"XHTML", "HTML", -1},
+		{"my_HTML HTML", "HTML", 8},
+		{"(HTML)", "HTML", 1},
+		{"C++ code", "C++", 0},
+		{"no match", "HTML", -1},
+	}
+	for _, tt := range tests {
+		if got := termIndex(tt.s, tt.term); got != tt.want {
+			t.Errorf("termIndex(%q, %q) = %d, want %d", tt.s, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestConvertCallouts(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts Options
+		want string
+	}{
+		{"alert", "> [!WARNING]\n> Be careful.", Options{}, "> :warning: *Warning*\n> Be careful."},
+		{"title", "> [!tip]- Try this\n> body", Options{}, "> :bulb: *Try this*\n> body"},
+		{"bold title", "> [!note] **Big** idea", Options{}, "> :memo: *Big idea*"},
+		{"unknown type", "> [!custom]", Options{}, "> :memo: *Custom*"},
+		{"ascii", "> [!note] Title\n> body", Options{ASCII: true}, "> *Title*\n> body"},
+		{"plain quote after", "> [!note]\n> body\n\n> quoted", Options{}, "> :memo: *Note*\n> body\n\n    quoted"},
+		{"plain quote before", "> quoted\n> [!note]\n> body", Options{}, "    quoted\n> :memo: *Note*\n> body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.in, tt.opts); got != tt.want {
+				t.Errorf("Convert(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertWikiLinks(t *testing.T) {
+	const wiki = "https://wiki.example.com/{slug}"
+	tests := []struct {
+		name string
+		in   string
+		opts Options
+		want string
+	}{
+		{"no template", "See [[My Page|the page]].", Options{}, "See the page."},
+		{"inline", "See [[My Page|the page]].", Options{WikiURL: wiki}, "See the page (https://wiki.example.com/my-page)."},
+		{"slack", "See [[My Page]].", Options{WikiURL: wiki, LinkStyle: "slack"}, "See <https://wiki.example.com/my-page|My Page>."},
+		{"text", "See [[My Page]].", Options{WikiURL: wiki, LinkStyle: "text"}, "See My Page."},
+		{"footnotes", "See [[My Page]].", Options{WikiURL: wiki, LinkStyle: "footnotes"}, "See My Page [1].\n\n[1] https://wiki.example.com/my-page\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.in, tt.opts); got != tt.want {
+				t.Errorf("Convert(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSpoilersQuote(t *testing.T) {
+	const label = "> :no_entry_sign: *Spoiler*: "
+	tests := []struct {
+		name, in, want string
+	}{
+		{"own line", "||the butler||", label + "the butler"},
+		{"text around", "It was ||the butler|| all along", "It was\n" + label + "the butler\nall along"},
+		{"period", "It was ||the butler||.", "It was\n" + label + "the butler."},
+		{"comma", "It was ||the butler||, sadly.", "It was\n" + label + "the butler,\nsadly."},
+		{"closing paren", "(it was ||the butler||)", "(it was\n" + label + "the butler)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertSpoilers(tt.in, "quote", "", false); got != tt.want {
+				t.Errorf("convertSpoilers(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertCriticMarkup(t *testing.T) {
+	tests := []struct {
+		mode, in, want string
+	}{
+		{"accept", "added and {--removed--} and new.", "added and and new."},
+		{"accept", "drop the {--last--}.", "drop the."},
+		{"accept", "{--Gone--} here", "here"},
+		{"accept", "a{--b--}c", "ac"},
+		{"accept", "line {--end--}\nnext", "line\nnext"},
+		{"reject", "{++added++} and removed.", "and removed."},
+		{"reject", "keep {++this++} {++and this++} out", "keep out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.in, func(t *testing.T) {
+			if got := convertCriticMarkup(tt.in, tt.mode); got != tt.want {
+				t.Errorf("convertCriticMarkup(%q, %q) = %q, want %q", tt.in, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package slackify
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Fidelity tells how faithfully a document converts to Slack: of its Nodes,
+// the blocks and inline nodes Walk visits, how many are Degraded because
+// Check warns about something in them
+type Fidelity struct {
+	Nodes    int
+	Degraded int
+}
+
+// Score returns the percentage of nodes that convert faithfully, 100 for a
+// document without nodes
+func (f Fidelity) Score() float64 {
+	if f.Nodes == 0 {
+		return 100
+	}
+	return 100 * float64(f.Nodes-f.Degraded) / float64(f.Nodes)
+}
+
+// CheckFidelity measures how faithfully markdown text converts with opts.
+// Each warning of Check degrades the innermost node it falls in, so a
+// paragraph with several raw HTML tags counts once.
+func CheckFidelity(text string, opts Options) Fidelity {
+	var nodes [][2]int
+	for _, span := range walkBlocks(text) {
+		nodes = append(nodes, [2]int{span.start, span.end})
+		if !span.inline {
+			continue
+		}
+		for _, loc := range walkInlineRegex.FindAllStringIndex(text[span.start:span.end], -1) {
+			nodes = append(nodes, [2]int{span.start + loc[0], span.start + loc[1]})
+		}
+	}
+
+	degraded := map[int]bool{}
+	for _, w := range Check(text, opts) {
+		offset := offsetOf(text, w.Line, w.Column)
+		// Inline nodes follow their block, so the last match is innermost
+		for i := len(nodes) - 1; i >= 0; i-- {
+			if offset >= nodes[i][0] && offset < nodes[i][1] {
+				degraded[i] = true
+				break
+			}
+		}
+	}
+	return Fidelity{Nodes: len(nodes), Degraded: len(degraded)}
+}
+
+// offsetOf returns the byte offset of a 1-based line and column in text, the
+// reverse of position
+func offsetOf(text string, line, column int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	for ; column > 1 && offset < len(text) && text[offset] != '\n'; column-- {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
@@ -0,0 +1,152 @@
+package slackify
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GFMExtensions are the GitHub Flavored Markdown extensions Options.GFM
+// turns on and off
+var GFMExtensions = []string{"tables", "strikethrough", "tasklists", "autolinks", "footnotes"}
+
+// gfmEnabled reports whether the extension name is on for the GFM option:
+// all are on by default, a leading - turns one off, and listing any name
+// without - turns off those not listed
+func gfmEnabled(extensions []string, name string) bool {
+	listed, only := false, false
+	for _, extension := range extensions {
+		extension = strings.TrimSpace(extension)
+		if off, ok := strings.CutPrefix(extension, "-"); ok {
+			if off == name {
+				return false
+			}
+			continue
+		}
+		only = true
+		listed = listed || extension == name
+	}
+	return !only || listed
+}
+
+// Patterns for the GFM extensions
+var (
+	strikethroughRegex = regexp.MustCompile(`~~([^~\n]+)~~`)
+	taskItemRegex      = regexp.MustCompile(`(?m)^(\s*(?:[-*+]|\d+[.)])[ \t]+)\[([ xX])\]([ \t])`)
+	wwwRegex           = regexp.MustCompile(`(^|[\s(*_~])(www\.[\w-]+(?:\.[\w-]+)+[^\s<>]*)`)
+	footnoteDefRegex   = regexp.MustCompile(`(?m)^\[\^([^\]\s]+)\]:[ \t]*(.*)(?:\n([ \t]*\n)?|$)`)
+	footnoteRefRegex   = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+	noteRegex          = regexp.MustCompile(`^\[(\d+)\] `)
+)
+
+// convertStrikethrough converts ~~struck~~ text to Slack's ~struck~
+func convertStrikethrough(text string) string {
+	return strikethroughRegex.ReplaceAllString(text, "~$1~")
+}
+
+// convertTaskLists replaces the [ ] and [x] boxes of task list items with
+// emoji, or leaves them as written when ascii is set
+func convertTaskLists(text string, ascii bool) string {
+	if ascii {
+		return text
+	}
+	return taskItemRegex.ReplaceAllStringFunc(text, func(item string) string {
+		m := taskItemRegex.FindStringSubmatch(item)
+		box := ":white_large_square:"
+		if m[2] != " " {
+			box = ":white_check_mark:"
+		}
+		return m[1] + box + m[3]
+	})
+}
+
+// convertAutolinks links bare www. addresses the way GitHub does, leaving
+// trailing punctuation out of the link. Bare http(s) URLs are left to
+// Slack, which links them itself.
+func convertAutolinks(text string) string {
+	return wwwRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := wwwRegex.FindStringSubmatch(match)
+		address := m[2]
+		trailing := ""
+		for len(address) > 0 && strings.ContainsRune(".,:;!?\"')*_~", rune(address[len(address)-1])) {
+			// A closing parenthesis stays when it balances one in the address
+			if address[len(address)-1] == ')' && strings.Count(address, "(") >= strings.Count(address, ")") {
+				break
+			}
+			trailing = address[len(address)-1:] + trailing
+			address = address[:len(address)-1]
+		}
+		return m[1] + "<http://" + address + "|" + address + ">" + trailing
+	})
+}
+
+// convertFootnotes numbers [^label] references in order of first use as
+// [1], [2] and so on, and moves the [^label]: definitions to a numbered list
+// at the end of text. References without a definition stay as written. With
+// preserve set the blank lines around definitions and ending text are kept.
+func convertFootnotes(text string, preserve bool) string {
+	definitions := map[string]string{}
+	var order []string
+	text = mapOutsideFences(text, func(s string) string {
+		return footnoteDefRegex.ReplaceAllStringFunc(s, func(def string) string {
+			m := footnoteDefRegex.FindStringSubmatch(def)
+			if _, ok := definitions[m[1]]; !ok {
+				definitions[m[1]] = strings.TrimSpace(m[2])
+				order = append(order, m[1])
+			}
+			if preserve {
+				return m[3]
+			}
+			return ""
+		})
+	})
+	if len(definitions) == 0 {
+		return text
+	}
+
+	var used []string
+	text = mapOutsideCode(text, func(s string) string {
+		return footnoteRefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+			label := footnoteRefRegex.FindStringSubmatch(ref)[1]
+			if _, ok := definitions[label]; !ok {
+				return ref
+			}
+			n := slices.Index(used, label)
+			if n < 0 {
+				used = append(used, label)
+				n = len(used) - 1
+			}
+			return fmt.Sprintf("[%d]", n+1)
+		})
+	})
+
+	// Definitions nobody refers to still get a number, after the others
+	for _, label := range order {
+		if !slices.Contains(used, label) {
+			used = append(used, label)
+		}
+	}
+	notes := make([]string, len(used))
+	for i, label := range used {
+		notes[i] = fmt.Sprintf("[%d] %s", i+1, definitions[label])
+	}
+	return appendSection(text, strings.Join(notes, "\n")+"\n", preserve)
+}
+
+// lastNote returns the highest number in the [n] notes ending text, or 0
+// when it does not end with any
+func lastNote(text string) int {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	last := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		m := noteRegex.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		n, _ := strconv.Atoi(m[1])
+		last = max(last, n)
+	}
+	return last
+}
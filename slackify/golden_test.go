@@ -0,0 +1,89 @@
+package slackify
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update rewrites the golden files with the current output
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenCases are the options testdata/document.md is converted with: the
+// default pipeline, then each opt-in stage or rendering choice on its own
+var goldenCases = []struct {
+	name string
+	opts Options
+}{
+	{"default", Options{}},
+	{"redact", Options{Redact: true}},
+	{"code-fallback", Options{CodeFallback: true}},
+	{"punctuation-ascii", Options{Punctuation: "ascii"}},
+	{"punctuation-unicode", Options{Punctuation: "unicode"}},
+	{"notion", Options{Dialect: "notion"}},
+	{"commonmark-blocks", Options{CommonMarkBlocks: true}},
+	{"reflow", Options{Reflow: true}},
+	{"abbreviations-inline", Options{Abbreviations: "inline"}},
+	{"abbreviations-glossary", Options{Abbreviations: "glossary"}},
+	{"critic-accept", Options{Critic: "accept"}},
+	{"critic-reject", Options{Critic: "reject"}},
+	{"critic-show", Options{Critic: "show"}},
+	{"gfm-none", Options{GFM: []string{"-tables", "-strikethrough", "-tasklists", "-autolinks", "-footnotes"}}},
+	{"clean-urls", Options{CleanURLs: true}},
+	{"blocked-hosts", Options{BlockedHosts: []string{"*.corp.example"}}},
+	{"tickets", Options{TicketLinks: []TicketLink{{`JIRA-\d+`, "https://jira.example.com/browse/$0"}}}},
+	{"commits", Options{CommitURL: "https://github.com/owner/repo/commit/{sha}"}},
+	{"usergroups", Options{Usergroups: map[string]string{"platform-team": "S0123ABC"}}},
+	{"wiki-url", Options{WikiURL: "https://wiki.example.com/{slug}"}},
+	{"links-slack", Options{LinkStyle: "slack"}},
+	{"links-footnotes", Options{LinkStyle: "footnotes"}},
+	{"heading-caps", Options{HeadingStyle: "caps"}},
+	{"ordered-lists-paren", Options{OrderedLists: "paren"}},
+	{"bullets", Options{Bullets: []string{"*", "-"}, Indent: 4}},
+	{"tables-plain", Options{TableMode: "plain"}},
+	{"tables-raw", Options{TableMode: "raw"}},
+	{"table-options", Options{TableSort: "-Qty", TableLimit: 2, TableColumns: []string{"Name", "Qty"}}},
+	{"spoilers-quote", Options{Spoilers: "quote"}},
+	{"spoilers-hide", Options{Spoilers: "hide"}},
+	{"inline-footnotes-parens", Options{InlineFootnotes: "parens"}},
+	{"highlight-emoji", Options{Highlight: ":large_yellow_circle:"}},
+	{"glossary", Options{Glossary: map[string]string{"release": "drop"}}},
+	{"preserve-spacing", Options{PreserveSpacing: true}},
+	{"compact", Options{Compact: true}},
+	{"truncate", Options{TruncateAt: 400, MoreURL: "https://example.com/notes"}},
+	{"ascii", Options{ASCII: true}},
+	{"no-emoji", Options{NoEmoji: true}},
+	{"escape", Options{Escape: true}},
+}
+
+func TestGolden(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "document.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Convert(string(input), tc.opts)
+			path := filepath.Join("testdata", "golden", tc.name+".txt")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("output differs from %s; run go test -update and review the diff\ngot:\n%s", path, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package slackify
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlNode is an element or text node of a parsed HTML document
+type htmlNode struct {
+	tag      string // lower-case element name, "" for text
+	attrs    map[string]string
+	text     string
+	children []*htmlNode
+}
+
+// htmlBlockTags are the elements rendered as blocks rather than inline text
+var htmlBlockTags = map[string]bool{
+	"html": true, "body": true, "div": true, "p": true, "section": true,
+	"article": true, "header": true, "footer": true, "main": true, "nav": true,
+	"aside": true, "figure": true, "figcaption": true, "center": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"pre": true, "blockquote": true, "table": true, "hr": true,
+	"head": true, "title": true, "script": true, "style": true, "noscript": true,
+}
+
+// htmlImplicitClose lists, per start tag, the open elements it closes
+var htmlImplicitClose = map[string]map[string]bool{
+	"li": {"li": true},
+	"p":  {"p": true},
+	"dt": {"dt": true, "dd": true},
+	"dd": {"dt": true, "dd": true},
+	"tr": {"tr": true},
+	"td": {"td": true, "th": true},
+	"th": {"td": true, "th": true},
+}
+
+// htmlVoidTags are the elements that never have content or an end tag
+var htmlVoidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// Patterns for the HTML tokenizer: a start or end tag, and an attribute
+// inside a start tag
+var (
+	htmlTokenRegex = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	htmlAttrRegex  = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+)))?`)
+)
+
+// parseHTML builds a tree from HTML the way browsers are forgiving about it:
+// a < or & that starts no tag or entity is text, void elements need no end
+// tag, unclosed elements end with their parent or the input, and the
+// content of <script> and <style> is not parsed
+func parseHTML(source string) *htmlNode {
+	root := &htmlNode{tag: "root"}
+	stack := []*htmlNode{root}
+	appendText := func(text string) {
+		if text == "" {
+			return
+		}
+		top := stack[len(stack)-1]
+		top.children = append(top.children, &htmlNode{text: html.UnescapeString(text)})
+	}
+
+	for source != "" {
+		i := strings.IndexByte(source, '<')
+		if i < 0 {
+			appendText(source)
+			break
+		}
+		appendText(source[:i])
+		source = source[i:]
+
+		// Comments, doctypes and processing instructions are skipped
+		switch {
+		case strings.HasPrefix(source, "<!--"):
+			end := strings.Index(source[4:], "-->")
+			if end < 0 {
+				return root
+			}
+			source = source[4+end+3:]
+			continue
+		case strings.HasPrefix(source, "<!") || strings.HasPrefix(source, "<?"):
+			end := strings.IndexByte(source, '>')
+			if end < 0 {
+				return root
+			}
+			source = source[end+1:]
+			continue
+		}
+
+		m := htmlTokenRegex.FindStringSubmatch(source)
+		if m == nil {
+			// A < that starts no tag, like 5 < 6, is text
+			appendText("<")
+			source = source[1:]
+			continue
+		}
+		source = source[len(m[0]):]
+		tag := strings.ToLower(m[2])
+
+		if m[1] == "/" {
+			// Close the nearest matching element; stray end tags are ignored
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		node := &htmlNode{tag: tag, attrs: map[string]string{}}
+		for _, attr := range htmlAttrRegex.FindAllStringSubmatch(m[3], -1) {
+			node.attrs[strings.ToLower(attr[1])] = html.UnescapeString(attr[2] + attr[3] + attr[4])
+		}
+		// An <li>, <p>, <tr> or cell start tag implicitly closes an open sibling
+		if closes := htmlImplicitClose[tag]; closes != nil && closes[stack[len(stack)-1].tag] {
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+		top.children = append(top.children, node)
+
+		switch {
+		case htmlVoidTags[tag] || strings.HasSuffix(m[3], "/"):
+		case tag == "script" || tag == "style":
+			// Raw text up to the end tag
+			end := strings.Index(strings.ToLower(source), "</"+tag)
+			if end < 0 {
+				end = len(source)
+			}
+			node.children = []*htmlNode{{text: source[:end]}}
+			source = source[end:]
+		default:
+			stack = append(stack, node)
+		}
+	}
+
+	return root
+}
+
+// htmlToMarkdown converts an HTML document or fragment to markdown, ready
+// for Convert
+func htmlToMarkdown(source string) (string, error) {
+	return htmlBlocks(parseHTML(source).children), nil
+}
+
+// htmlBlocks renders a list of nodes as blocks separated by blank lines,
+// gathering runs of inline nodes into paragraphs
+func htmlBlocks(nodes []*htmlNode) string {
+	parts := []string{}
+	var paragraph strings.Builder
+
+	flush := func() {
+		lines := strings.Split(paragraph.String(), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSpace(line)
+		}
+		if text := strings.TrimSpace(strings.Join(lines, "\n")); text != "" {
+			parts = append(parts, text)
+		}
+		paragraph.Reset()
+	}
+
+	for _, node := range nodes {
+		if htmlBlockTags[node.tag] {
+			flush()
+			if text := htmlBlock(node); text != "" {
+				parts = append(parts, text)
+			}
+		} else {
+			paragraph.WriteString(htmlInline(node))
+		}
+	}
+	flush()
+
+	return strings.Join(parts, "\n\n")
+}
+
+// htmlBlock renders a block-level element
+func htmlBlock(node *htmlNode) string {
+	switch node.tag {
+	case "head", "title", "script", "style", "noscript":
+		return ""
+
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		// The converter knows three heading levels
+		level := int(node.tag[1] - '0')
+		if level > 3 {
+			level = 3
+		}
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(htmlInlines(node.children))
+
+	case "ul", "ol":
+		items := []string{}
+		number := 1
+		for _, child := range node.children {
+			if child.tag != "li" {
+				continue
+			}
+			marker := "- "
+			if node.tag == "ol" {
+				marker = fmt.Sprintf("%d. ", number)
+				number++
+			}
+			items = append(items, marker+indentContinuation(htmlListItem(child), "  "))
+		}
+		return strings.Join(items, "\n")
+
+	case "li":
+		return "- " + indentContinuation(htmlListItem(node), "  ")
+
+	case "dl":
+		items := []string{}
+		for _, child := range node.children {
+			switch child.tag {
+			case "dt":
+				items = append(items, "**"+strings.TrimSpace(htmlInlines(child.children))+"**")
+			case "dd":
+				items = append(items, "  "+strings.TrimSpace(htmlInlines(child.children)))
+			}
+		}
+		return strings.Join(items, "\n")
+
+	case "pre":
+		return "```\n" + strings.Trim(htmlText(node), "\n") + "\n```"
+
+	case "blockquote":
+		return prefixLines(htmlBlocks(node.children), "> ")
+
+	case "hr":
+		return "---"
+
+	case "table":
+		return htmlTable(node)
+	}
+
+	return htmlBlocks(node.children)
+}
+
+// htmlListItem renders the content of a list item, keeping nested lists
+// on the lines right below it
+func htmlListItem(node *htmlNode) string {
+	return strings.ReplaceAll(htmlBlocks(node.children), "\n\n", "\n")
+}
+
+// htmlTable renders a data table as a markdown pipe table. Layout tables
+// (nested tables or a single column, as in most HTML email) are unwrapped
+// into their cell contents instead.
+func htmlTable(table *htmlNode) string {
+	var rows [][]*htmlNode
+	var collect func(node *htmlNode)
+	collect = func(node *htmlNode) {
+		for _, child := range node.children {
+			switch child.tag {
+			case "thead", "tbody", "tfoot":
+				collect(child)
+			case "tr":
+				cells := []*htmlNode{}
+				for _, cell := range child.children {
+					if cell.tag == "td" || cell.tag == "th" {
+						cells = append(cells, cell)
+					}
+				}
+				rows = append(rows, cells)
+			}
+		}
+	}
+	collect(table)
+
+	layout := true
+	for _, row := range rows {
+		if len(row) > 1 {
+			layout = false
+		}
+		for _, cell := range row {
+			if containsTag(cell, "table") {
+				layout = true
+			}
+		}
+	}
+
+	if layout {
+		parts := []string{}
+		for _, row := range rows {
+			for _, cell := range row {
+				if text := htmlBlocks(cell.children); text != "" {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	lines := []string{}
+	for i, row := range rows {
+		cells := []string{}
+		for _, cell := range row {
+			// Line breaks survive as <br>, which the table formatter understands
+			lines := strings.Split(strings.TrimSpace(htmlInlines(cell.children)), "\n")
+			for i, line := range lines {
+				lines[i] = strings.Join(strings.Fields(line), " ")
+			}
+			cells = append(cells, strings.ReplaceAll(strings.Join(lines, "<br>"), "|", "\\|"))
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat("---|", len(cells)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tableTagRegex finds opening and closing <table> tags
+var tableTagRegex = regexp.MustCompile(`(?i)<(/?)table\b[^>]*>`)
+
+// htmlTableSpans returns the byte ranges of the outermost <table> elements
+// in text
+func htmlTableSpans(text string) [][]int {
+	var spans [][]int
+	depth, start := 0, 0
+	for _, loc := range tableTagRegex.FindAllStringSubmatchIndex(text, -1) {
+		switch {
+		case loc[3] == loc[2]:
+			if depth == 0 {
+				start = loc[0]
+			}
+			depth++
+		case depth > 0:
+			if depth--; depth == 0 {
+				spans = append(spans, []int{start, loc[1]})
+			}
+		}
+	}
+	return spans
+}
+
+// convertHTMLTables replaces the <table> elements in markdown text with
+// markdown tables, so they go through the same formatting as pipe tables
+func convertHTMLTables(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range htmlTableSpans(text) {
+		root := parseHTML(text[span[0]:span[1]])
+		if len(root.children) == 0 || root.children[0].tag != "table" {
+			continue
+		}
+		b.WriteString(text[last:span[0]])
+		b.WriteString(htmlTable(root.children[0]))
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// containsTag reports whether any descendant of node is a tag element
+func containsTag(node *htmlNode, tag string) bool {
+	for _, child := range node.children {
+		if child.tag == tag || containsTag(child, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlInlines renders a list of nodes as inline markdown
+func htmlInlines(nodes []*htmlNode) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		b.WriteString(htmlInline(node))
+	}
+	return b.String()
+}
+
+// spaceRegex finds runs of whitespace
+var spaceRegex = regexp.MustCompile(`\s+`)
+
+// htmlInline renders a text node or inline element
+func htmlInline(node *htmlNode) string {
+	// Emphasis markers must hug the text, so surrounding spaces move outside
+	wrap := func(marker string) string {
+		inner := htmlInlines(node.children)
+		trimmed := strings.TrimSpace(inner)
+		if trimmed == "" {
+			return inner
+		}
+		i := strings.Index(inner, trimmed)
+		return inner[:i] + marker + trimmed + marker + inner[i+len(trimmed):]
+	}
+
+	switch node.tag {
+	case "":
+		return spaceRegex.ReplaceAllString(strings.ReplaceAll(node.text, "\u00a0", " "), " ")
+	case "br":
+		return "\n"
+	case "strong", "b":
+		return wrap("**")
+	case "em", "i":
+		return wrap("*")
+	case "s", "strike", "del":
+		return wrap("~~")
+	case "code", "kbd", "tt", "samp":
+		return "`" + htmlText(node) + "`"
+	case "sup":
+		return "^" + strings.TrimSpace(htmlInlines(node.children)) + "^"
+	case "sub":
+		return "~" + strings.TrimSpace(htmlInlines(node.children)) + "~"
+	case "a":
+		text := strings.TrimSpace(htmlInlines(node.children))
+		href := node.attrs["href"]
+		if href == "" || strings.HasPrefix(href, "#") || href == text {
+			return text
+		}
+		if text == "" {
+			return href
+		}
+		return "[" + text + "](" + href + ")"
+	case "img":
+		if src := node.attrs["src"]; src != "" && !strings.HasPrefix(src, "data:") {
+			alt := node.attrs["alt"]
+			if alt == "" {
+				alt = "image"
+			}
+			return "[" + alt + "](" + src + ")"
+		}
+		return node.attrs["alt"]
+	case "script", "style":
+		return ""
+	}
+
+	if htmlBlockTags[node.tag] {
+		return " " + htmlInlines(node.children) + " "
+	}
+	return htmlInlines(node.children)
+}
+
+// htmlText returns the raw text content of a node, whitespace included
+func htmlText(node *htmlNode) string {
+	if node.tag == "" {
+		return node.text
+	}
+	if node.tag == "br" {
+		return "\n"
+	}
+	var b strings.Builder
+	for _, child := range node.children {
+		b.WriteString(htmlText(child))
+	}
+	return b.String()
+}
+
+// indentContinuation indents every line of text but the first
+func indentContinuation(text string, indent string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+indent)
+}
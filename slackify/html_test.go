@@ -0,0 +1,35 @@
+package slackify
+
+import "testing"
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"unescaped ampersand and less-than", "<p>R&D and 5 < 6</p>", "R&D and 5 < 6"},
+		{"entities", "<p>&copy; 2024 &amp; &lt;tags&gt; &#8212; ok</p>", "© 2024 & <tags> — ok"},
+		{"script content", "<p>Before</p><script>if (a < b && c) { x() }</script><p>After</p>", "Before\n\nAfter"},
+		{"style content", "<style>p > a { color: red }</style><p>Text</p>", "Text"},
+		{"void elements", "<p>one<br>two<br/>three</p><hr><p>four</p>", "one\ntwo\nthree\n\n---\n\nfour"},
+		{"image", `<p><img src="a.png" alt="A picture"></p>`, "[A picture](a.png)"},
+		{"unclosed paragraphs", "<p>first<p>second", "first\n\nsecond"},
+		{"unclosed list items", "<ul><li>one<li>two</ul>", "- one\n- two"},
+		{"unquoted attributes", "<a href=https://example.com>link</a>", "[link](https://example.com)"},
+		{"attribute entities", `<a href="https://example.com/?a=1&amp;b=2">link</a>`, "[link](https://example.com/?a=1&b=2)"},
+		{"stray end tag", "<p>text</div></p>", "text"},
+		{"comment", "<p>a<!-- <b>hidden</b> -->b</p>", "ab"},
+		{"doctype", "<!DOCTYPE html><html><body><h1>Title</h1></body></html>", "# Title"},
+		{"unclosed at end", "<div><p><strong>bold", "**bold**"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := htmlToMarkdown(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("htmlToMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package slackify
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// matchesHost reports whether host matches one of patterns. A pattern is a
+// host name, which also covers its subdomains, or a glob like *.corp.example
+func matchesHost(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, host); ok {
+				return true
+			}
+		} else if host == pattern || strings.HasSuffix(host, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalLink reports whether rawURL points at a host that must not be
+// linked: one in opts.BlockedHosts, or any outside opts.AllowedHosts when
+// that is set. Relative links are never internal.
+func internalLink(rawURL string, opts Options) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if matchesHost(u.Hostname(), opts.BlockedHosts) {
+		return true
+	}
+	return len(opts.AllowedHosts) > 0 && !matchesHost(u.Hostname(), opts.AllowedHosts)
+}
+
+// linkOrURLRegex finds markdown links and images, autolinks and bare URLs
+var linkOrURLRegex = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)|<(https?://[^>\s]+)>|https?://[^\s<>()\]]+`)
+
+// filterLinks removes links to internal hosts from markdown text. Inline
+// links and images keep their text, followed by "(internal link)" when
+// opts.InternalLinks is "marker"; bare URLs and autolinks become that marker.
+func filterLinks(text string, opts Options) string {
+	return linkOrURLRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := linkOrURLRegex.FindStringSubmatch(match)
+		inline := strings.HasSuffix(match, ")") && m[2] != ""
+		target := match
+		switch {
+		case inline:
+			target = m[2]
+		case m[3] != "":
+			target = m[3]
+		}
+		if !internalLink(target, opts) {
+			return match
+		}
+		if !inline {
+			return "(internal link)"
+		}
+		if opts.InternalLinks == "marker" {
+			return m[1] + " (internal link)"
+		}
+		return m[1]
+	})
+}
+
+// trackingParams are query parameters that only carry campaign and click
+// tracking data; names ending in * are prefixes
+var trackingParams = []string{
+	"utm_*", "gclid", "gclsrc", "dclid", "fbclid", "msclkid", "mc_cid", "mc_eid",
+	"yclid", "igshid", "_hsenc", "_hsmi", "mkt_tok", "oly_anon_id", "oly_enc_id",
+	"vero_id", "wickedid", "_ga", "_gl", "ref_src", "s_cid",
+}
+
+// cleanURL removes tracking parameters from rawURL, keeping the rest of it
+// as written
+func cleanURL(rawURL string) string {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	var kept []string
+	for _, param := range strings.Split(query, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		name = strings.ToLower(name)
+		tracking := slices.ContainsFunc(trackingParams, func(p string) bool {
+			prefix, isPrefix := strings.CutSuffix(p, "*")
+			return name == p || (isPrefix && strings.HasPrefix(name, prefix))
+		})
+		if !tracking && param != "" {
+			kept = append(kept, param)
+		}
+	}
+
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
+
+// urlRegex finds bare URLs
+var urlRegex = regexp.MustCompile(`https?://[^\s<>()\]|]+`)
+
+// cleanURLs removes tracking parameters from the URLs in markdown text
+func cleanURLs(text string) string {
+	return urlRegex.ReplaceAllStringFunc(text, cleanURL)
+}
+
+// Shortener turns long URLs into short ones. Shorten may be called from
+// several goroutines at once; ctx is the conversion's context, done when
+// the caller of ConvertContext gives up.
+type Shortener interface {
+	Shorten(ctx context.Context, url string) (string, error)
+}
+
+// shortenURLs replaces the URLs in markdown text longer than limit
+// characters (60 when limit is not positive) with what shortener returns,
+// keeping those it fails on and the rest once ctx is done
+func shortenURLs(ctx context.Context, text string, shortener Shortener, limit int) string {
+	if limit <= 0 {
+		limit = 60
+	}
+	return urlRegex.ReplaceAllStringFunc(text, func(long string) string {
+		if len(long) <= limit || ctx.Err() != nil {
+			return long
+		}
+		if short, err := shortener.Shorten(ctx, long); err == nil && short != "" {
+			return short
+		}
+		return long
+	})
+}
+
+// TicketLink links ticket references matching Pattern, like JIRA-\d+ or
+// #(\d+), to URL, a template where $0 is the whole reference and $1 and on
+// its submatches
+type TicketLink struct {
+	Pattern string
+	URL     string
+}
+
+// ticketRule is a TicketLink with its pattern compiled
+type ticketRule struct {
+	pattern *regexp.Regexp
+	url     string
+}
+
+// ticketRules compiles links, skipping patterns that do not compile
+func ticketRules(links []TicketLink) []ticketRule {
+	var rules []ticketRule
+	for _, link := range links {
+		if re, err := regexp.Compile(link.Pattern); err == nil && link.URL != "" {
+			rules = append(rules, ticketRule{re, link.URL})
+		}
+	}
+	return rules
+}
+
+// linkSkipRegex finds what ticket references and commits inside are left
+// alone: markdown links, Slack links, bare URLs and held passthrough text
+var linkSkipRegex = regexp.MustCompile(`!?\[[^\]]*\]\([^)]*\)|<[^<>\n]*>|https?://\S+|\x{E000}\d+\x{E001}`)
+
+// linkTickets turns the ticket references rules find in text into Slack
+// <url|reference> links
+func linkTickets(text string, rules []ticketRule) string {
+	for _, rule := range rules {
+		text = linkMatches(text, rule.pattern, func(loc []int) (string, string) {
+			return string(rule.pattern.ExpandString(nil, rule.url, text, loc)), text[loc[0]:loc[1]]
+		})
+	}
+	return text
+}
+
+// commitRegex finds what may be a commit SHA: 7 to 40 lowercase hex digits
+var commitRegex = regexp.MustCompile(`[0-9a-f]{7,40}`)
+
+// linkCommits turns commit SHAs in text into Slack links to urlTemplate,
+// with {sha} replaced by the full SHA, shown by their first 7 characters.
+// Runs of hex digits without both a digit and a letter, like 1234567 or
+// defaced, are words or numbers rather than SHAs and stay as written.
+func linkCommits(text string, urlTemplate string) string {
+	return linkMatches(text, commitRegex, func(loc []int) (string, string) {
+		sha := text[loc[0]:loc[1]]
+		if !strings.ContainsAny(sha, "0123456789") || !strings.ContainsAny(sha, "abcdef") {
+			return "", ""
+		}
+		return strings.ReplaceAll(urlTemplate, "{sha}", sha), sha[:7]
+	})
+}
+
+// handleRegex finds what may be a user group handle, like @platform-team
+var handleRegex = regexp.MustCompile(`@[a-zA-Z0-9](?:[\w.-]*[a-zA-Z0-9_])?`)
+
+// mentionUsergroups turns the @handles of the user groups in groups, matched
+// without regard to case, into Slack <!subteam^ID|@handle> mentions. Other
+// handles, and @ in email addresses, stay as written.
+func mentionUsergroups(text string, groups map[string]string) string {
+	return linkMatches(text, handleRegex, func(loc []int) (string, string) {
+		handle := text[loc[0]+1 : loc[1]]
+		id, ok := groups[handle]
+		if !ok {
+			id, ok = groups[strings.ToLower(handle)]
+		}
+		if !ok {
+			return "", ""
+		}
+		return "!subteam^" + id, "@" + handle
+	})
+}
+
+// linkMatches turns what pattern finds in text into Slack <url|label> links
+// with the URL and label link returns for each match, or leaves the match
+// as written when link returns no URL. Matches inside links, URLs or held
+// passthrough text, or that do not stand apart from the word, path or number
+// around them, are left alone, so #12 in a URL fragment or ABC-1 in XABC-12
+// stay as written.
+func linkMatches(text string, pattern *regexp.Regexp, link func(loc []int) (url, label string)) string {
+	skip := linkSkipRegex.FindAllStringIndex(text, -1)
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] == loc[1] || !standsApart(text, loc[0], loc[1]) ||
+			slices.ContainsFunc(skip, func(span []int) bool { return loc[0] < span[1] && loc[1] > span[0] }) {
+			continue
+		}
+		url, label := link(loc)
+		if url == "" {
+			continue
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString("<" + url + "|" + label + ">")
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// standsApart reports whether text[start:end] is not part of a longer word,
+// number, path or hyphenated name
+func standsApart(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_/-.#", r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package slackify
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type ctxKey struct{}
+
+// recordingShortener shortens every URL to https://sho.rt/<n>, recording the
+// context value each call saw
+type recordingShortener struct {
+	seen []any
+}
+
+func (s *recordingShortener) Shorten(ctx context.Context, url string) (string, error) {
+	s.seen = append(s.seen, ctx.Value(ctxKey{}))
+	return "https://sho.rt/" + string(rune('0'+len(s.seen))), nil
+}
+
+func TestShortenerContext(t *testing.T) {
+	const long = "https://example.com/a/very/long/path/that/goes/on/and/on/for/a/while?with=query"
+	in := "See " + long + " and [docs](" + long + "&page=2)."
+
+	t.Run("passed through", func(t *testing.T) {
+		shortener := &recordingShortener{}
+		ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+		var out strings.Builder
+		if err := New(WithShortener(shortener, 40)).ConvertContext(ctx, strings.NewReader(in), &out); err != nil {
+			t.Fatal(err)
+		}
+		if want := "See https://sho.rt/1 and docs (https://sho.rt/2)."; out.String() != want {
+			t.Errorf("ConvertContext = %q, want %q", out.String(), want)
+		}
+		if len(shortener.seen) != 2 || shortener.seen[0] != "request" || shortener.seen[1] != "request" {
+			t.Errorf("Shorten saw context values %v, want the caller's on both calls", shortener.seen)
+		}
+	})
+
+	t.Run("done", func(t *testing.T) {
+		shortener := &recordingShortener{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if shortenURLs(ctx, in, shortener, 40) != in || len(shortener.seen) != 0 {
+			t.Errorf("shortenURLs called the shortener %d times after ctx was done", len(shortener.seen))
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package slackify
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Patterns for the Slack markup Lint checks
+var (
+	lintCodeRegex    = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`")
+	lintSpecialRegex = regexp.MustCompile(`^<(?:@[UW][A-Z0-9]+|#C[A-Z0-9]+|!(?:here|channel|everyone)|!subteam\^S[A-Z0-9]+|!date\^\d+\^[^|>]+(?:\^[^|>]+)?)(?:\|[^<>]*)?>$`)
+)
+
+// Lint reports the problems in text already written as Slack mrkdwn:
+// unbalanced *, _ and ~ markers, &, < and > that Slack needs escaped,
+// malformed <@user>, <#channel> and <!special> mentions, and text longer
+// than a message should be
+func Lint(text string) []Warning {
+	var warnings []Warning
+	warn := func(offset int, code string, format string, args ...any) {
+		line, column := position(text, offset)
+		warnings = append(warnings, Warning{code, line, column, fmt.Sprintf(format, args...)})
+	}
+
+	// Code is shown as written, so nothing in it needs escaping or closing
+	code := lintCodeRegex.FindAllStringIndex(text, -1)
+	inCode := func(offset int) bool {
+		for _, loc := range code {
+			if offset >= loc[0] && offset < loc[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Links and mentions hold URLs and IDs whose characters are not markup
+	var markup [][]int
+	for _, loc := range slackControlRegex.FindAllStringIndex(text, -1) {
+		if inCode(loc[0]) {
+			continue
+		}
+		match := text[loc[0]:loc[1]]
+		switch {
+		case match == ">" && (loc[0] == 0 || text[loc[0]-1] == '\n'):
+			// A quote marker
+		case match == "&" || match == "<" || match == ">":
+			warn(loc[0], WarnUnescaped, "%s must be escaped as %s", match, escapeSlack(match))
+		case strings.HasPrefix(match, "<"):
+			markup = append(markup, loc)
+			if strings.ContainsAny(match[1:2], "@#!") && !lintSpecialRegex.MatchString(match) {
+				warn(loc[0], WarnMention, "%s is not a valid mention; use <@U123>, <#C123>, <!here> or <!subteam^S123>", match)
+			}
+		}
+	}
+	inMarkup := func(offset int) bool {
+		for _, loc := range markup {
+			if offset >= loc[0] && offset < loc[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for _, marker := range []byte{'*', '_', '~'} {
+			if at := unclosedMarker(line, marker, func(i int) bool {
+				return inCode(offset+i) || inMarkup(offset+i)
+			}); at >= 0 {
+				warn(offset+at, WarnUnbalanced, "%c opens formatting that is not closed on the same line", marker)
+			}
+		}
+		offset += len(line)
+	}
+
+	if n := utf8.RuneCountInString(text); n > MessageHardLimit {
+		warn(runeOffset(text, MessageHardLimit), WarnTooLong, "text is %d characters; Slack cuts messages off at %d", n, MessageHardLimit)
+	} else if n > MessageLimit {
+		warn(runeOffset(text, MessageLimit), WarnTooLong, "text is %d characters, over the %d Slack recommends for a message", n, MessageLimit)
+	}
+
+	slices.SortStableFunc(warnings, func(a, b Warning) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return warnings
+}
+
+// unclosedMarker returns the offset in line of the first marker that opens
+// formatting, following a space or punctuation and followed by text, with no
+// marker closing it later on the line, or -1. Markers at offsets skip
+// reports are ignored.
+func unclosedMarker(line string, marker byte, skip func(int) bool) int {
+	isText := func(i int) bool {
+		if i < 0 || i >= len(line) {
+			return false
+		}
+		r, _ := utf8.DecodeRuneInString(line[i:])
+		return !unicode.IsSpace(r) && !unicode.IsPunct(r)
+	}
+
+	open := -1
+	for i := 0; i < len(line); i++ {
+		if line[i] != marker || skip(i) {
+			continue
+		}
+		before, after := i > 0 && line[i-1] != ' ' && line[i-1] != '\t', i+1 < len(line) && line[i+1] != ' ' && line[i+1] != '\n'
+		switch {
+		case open < 0 && !isText(i-1) && (i == 0 || line[i-1] != marker) && after:
+			open = i
+		case open >= 0 && before && !isText(i+1):
+			open = -1
+		}
+	}
+	return open
+}
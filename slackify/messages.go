@@ -0,0 +1,159 @@
+package slackify
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// titleMarkupRegex matches the markdown around a heading's words: emphasis
+// and code markers, and the target of a link
+var titleMarkupRegex = regexp.MustCompile("[*_~`]+|\\]\\([^)]*\\)|\\[")
+
+// ConvertMessages converts markdown text to Slack mrkdwn split into messages
+// of at most MessageLimit characters. With Options.Index, several messages
+// are led by an index of the sections in them, and with Options.MaxMessages
+// the messages past the limit are dropped with a notice saying so.
+// Options.PartNumbers and Options.Footer label each message but the index.
+func (c *Converter) ConvertMessages(text string) []string {
+	limit := MessageLimit
+	if c.opts.PartNumbers {
+		// Leave room for the label
+		limit -= len("_part 100/100_\n")
+	}
+	if c.opts.Footer != "" {
+		limit -= len("\n\n" + footerLine(c.opts.Footer))
+	}
+	messages := Split(c.Convert(text), limit)
+	posted, indexed := c.postedMessages(len(messages))
+
+	var index string
+	if indexed {
+		index = c.Convert(indexMarkdown(headingTitles(text), messages, posted, func(title string) string {
+			return title
+		}))
+		index = strings.TrimRight(index, "\n")
+	}
+	if posted < len(messages) {
+		notice := c.Convert(truncationNotice(posted, len(messages), c.opts))
+		messages = messages[:posted]
+		messages[posted-1] += "\n\n" + strings.TrimRight(notice, "\n")
+	}
+	if c.opts.PartNumbers && posted > 1 {
+		for i := range messages {
+			messages[i] = "_" + partLabel(i+1, posted) + "_\n" + messages[i]
+		}
+	}
+	if c.opts.Footer != "" {
+		for i := range messages {
+			messages[i] += "\n\n" + footerLine(c.opts.Footer)
+		}
+	}
+	if index != "" {
+		messages = append([]string{index}, messages...)
+	}
+	return messages
+}
+
+// postedMessages returns how many of total messages to post within
+// Options.MaxMessages, and whether an index leads them. The index takes one
+// of the places, unless there is only one.
+func (c *Converter) postedMessages(total int) (posted int, indexed bool) {
+	indexed = c.opts.Index && total > 1
+	limit := c.opts.MaxMessages
+	if limit <= 0 {
+		return total, indexed
+	}
+	if indexed && limit == 1 {
+		indexed = false
+	}
+	if indexed {
+		limit--
+	}
+	return min(total, limit), indexed
+}
+
+// partLabel returns the label of message i of n with Options.PartNumbers
+func partLabel(i, n int) string {
+	return fmt.Sprintf("part %d/%d", i, n)
+}
+
+// footerLine returns the subdued line of Options.Footer
+func footerLine(footer string) string {
+	return "_" + footer + "_"
+}
+
+// truncationNotice returns the markdown noting that only posted of total
+// messages were kept
+func truncationNotice(posted, total int, opts Options) string {
+	dash := "\u2014"
+	if opts.ASCII {
+		dash = "-"
+	}
+	return fmt.Sprintf("_(truncated %s %d of %d sections posted)_", dash, posted, total)
+}
+
+// richTextIndex returns the payload of an index of the sections in the
+// first posted payloads
+func (c *Converter) richTextIndex(text string, payloads []string, posted int) (string, error) {
+	// Titles appear in payloads as JSON strings
+	index := indexMarkdown(headingTitles(text), payloads, posted, func(title string) string {
+		quoted, _ := json.Marshal(title)
+		return strings.Trim(string(quoted), `"`)
+	})
+	blocks, err := c.ConvertToBlocks(index)
+	if err != nil {
+		return "", err
+	}
+	return payload(blocks)
+}
+
+// headingTitles returns the text of the ATX headings in markdown text,
+// outside code blocks
+func headingTitles(text string) []string {
+	var titles []string
+	mapOutsideFences(text, func(s string) string {
+		for _, line := range strings.Split(s, "\n") {
+			if m := richHeadingRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil && m[1] != "" {
+				titles = append(titles, m[1])
+			}
+		}
+		return s
+	})
+	return titles
+}
+
+// indexMarkdown lists titles with the part of messages each starts in,
+// leaving out those past the first posted parts. A title is found in the
+// first message from the previous title's on that holds its words, as encode
+// writes them, ignoring case and markup; one that cannot be found is listed
+// with the part before it.
+func indexMarkdown(titles []string, messages []string, posted int, encode func(string) string) string {
+	var b strings.Builder
+	plural := "s"
+	if posted == 1 {
+		plural = ""
+	}
+	fmt.Fprintf(&b, "**Contents** (%d part%s)\n\n", posted, plural)
+	words := make([]string, len(messages))
+	for i, message := range messages {
+		words[i] = strings.ToLower(titleMarkupRegex.ReplaceAllString(message, ""))
+	}
+	part := 0
+	for _, title := range titles {
+		find := strings.ToLower(encode(strings.TrimSpace(titleMarkupRegex.ReplaceAllString(title, ""))))
+		for i := part; i < len(messages); i++ {
+			if strings.Contains(words[i], find) {
+				part = i
+				break
+			}
+		}
+		if part >= posted {
+			break
+		}
+		// Links in a title would only clutter the list
+		fmt.Fprintf(&b, "- %s (part %d of %d)\n", linkRegex.ReplaceAllString(title, "$1"), part+1, posted)
+	}
+	return b.String()
+}
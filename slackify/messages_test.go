@@ -0,0 +1,76 @@
+package slackify
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvertMessagesFooter(t *testing.T) {
+	long := strings.Repeat(strings.Repeat("word ", 200)+"\n\n", 6)
+	tests := []struct {
+		name string
+		in   string
+		opts Options
+		want []string // the start and end of each message
+	}{
+		{"one message", "Hello", Options{Footer: "from notes.md"}, []string{"Hello", "Hello\n\n_from notes.md_"}},
+		{"split", long, Options{Footer: "from notes.md", PartNumbers: true}, []string{
+			"_part 1/2_\nword", "word\n\n_from notes.md_",
+			"_part 2/2_\nword", "word\n\n_from notes.md_",
+		}},
+		{"after the notice", long, Options{Footer: "from notes.md", MaxMessages: 1}, []string{"word", "posted)_\n\n_from notes.md_"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := New(WithOptions(tt.opts)).ConvertMessages(tt.in)
+			if len(messages) != len(tt.want)/2 {
+				t.Fatalf("ConvertMessages returned %d messages, want %d", len(messages), len(tt.want)/2)
+			}
+			for i, message := range messages {
+				if n := len(message); n > MessageLimit {
+					t.Errorf("message %d is %d characters, over MessageLimit", i+1, n)
+				}
+				if start, end := tt.want[2*i], tt.want[2*i+1]; !strings.HasPrefix(message, start) || !strings.HasSuffix(message, end) {
+					t.Errorf("message %d = %q..%q, want %q..%q", i+1, head(message), tail(message), start, end)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertRichTextFooter(t *testing.T) {
+	var long strings.Builder
+	for i := 0; i < MaxBlocks; i++ {
+		long.WriteString("# Section\n\nText\n\n")
+	}
+	payloads, err := ConvertRichText(long.String(), Options{Footer: "from notes.md", PartNumbers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 3 {
+		t.Fatalf("ConvertRichText returned %d payloads, want 3", len(payloads))
+	}
+	for i, p := range payloads {
+		var message struct{ Blocks []Block }
+		if err := json.Unmarshal([]byte(p), &message); err != nil {
+			t.Fatal(err)
+		}
+		blocks := message.Blocks
+		if len(blocks) > MaxBlocks {
+			t.Errorf("payload %d has %d blocks, over MaxBlocks", i+1, len(blocks))
+		}
+		last := blocks[len(blocks)-1]
+		if last.Type != "context" || last.Elements[0].Text != "_from notes.md_" {
+			t.Errorf("payload %d ends with %+v, want the footer's context block", i+1, last)
+		}
+	}
+}
+
+func head(s string) string {
+	return s[:min(len(s), 20)]
+}
+
+func tail(s string) string {
+	return s[max(0, len(s)-20):]
+}
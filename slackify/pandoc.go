@@ -0,0 +1,466 @@
+package slackify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pandocElement is a node of pandoc's JSON AST: a type tag and its contents
+type pandocElement struct {
+	T string          `json:"t"`
+	C json.RawMessage `json:"c"`
+}
+
+// pandocDocument is the top level of `pandoc -t json` output
+type pandocDocument struct {
+	APIVersion []int           `json:"pandoc-api-version"`
+	Blocks     []pandocElement `json:"blocks"`
+}
+
+// pandocRenderer renders pandoc AST nodes back to markdown, collecting
+// footnotes so they can be listed after the document
+type pandocRenderer struct {
+	notes []string
+}
+
+// pandocToMarkdown renders a pandoc JSON AST document to markdown, ready for
+// Convert
+func pandocToMarkdown(data []byte) (string, error) {
+	var doc pandocDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, column := position(string(data), int(syntaxErr.Offset))
+			return "", fmt.Errorf("invalid pandoc JSON at line %d, column %d: %v", line, column, err)
+		}
+		return "", fmt.Errorf("invalid pandoc JSON: %v", err)
+	}
+	if len(doc.APIVersion) < 2 || doc.APIVersion[0] != 1 || doc.APIVersion[1] < 22 {
+		return "", fmt.Errorf("unsupported pandoc API version %v (need 1.22 or later)", doc.APIVersion)
+	}
+
+	r := &pandocRenderer{}
+	text, err := r.blocks(doc.Blocks)
+	if err != nil {
+		return "", err
+	}
+
+	if len(r.notes) > 0 {
+		notes := []string{}
+		for i, note := range r.notes {
+			notes = append(notes, fmt.Sprintf("[%d] %s", i+1, note))
+		}
+		text += "\n\n" + strings.Join(notes, "\n")
+	}
+	return text, nil
+}
+
+// blocks renders a list of blocks separated by blank lines
+func (r *pandocRenderer) blocks(blocks []pandocElement) (string, error) {
+	rendered := []string{}
+	for _, block := range blocks {
+		text, err := r.block(block)
+		if err != nil {
+			return "", err
+		}
+		if text != "" {
+			rendered = append(rendered, text)
+		}
+	}
+	return strings.Join(rendered, "\n\n"), nil
+}
+
+// block renders a single block element
+func (r *pandocRenderer) block(block pandocElement) (string, error) {
+	switch block.T {
+	case "Plain", "Para":
+		var inlines []pandocElement
+		if err := json.Unmarshal(block.C, &inlines); err != nil {
+			return "", err
+		}
+		return r.inlines(inlines)
+
+	case "LineBlock":
+		var lines [][]pandocElement
+		if err := json.Unmarshal(block.C, &lines); err != nil {
+			return "", err
+		}
+		rendered := []string{}
+		for _, line := range lines {
+			text, err := r.inlines(line)
+			if err != nil {
+				return "", err
+			}
+			rendered = append(rendered, text)
+		}
+		return strings.Join(rendered, "  \n"), nil
+
+	case "CodeBlock":
+		var content []json.RawMessage
+		if err := json.Unmarshal(block.C, &content); err != nil || len(content) != 2 {
+			return "", fmt.Errorf("malformed CodeBlock")
+		}
+		var code string
+		if err := json.Unmarshal(content[1], &code); err != nil {
+			return "", err
+		}
+		return "```\n" + code + "\n```", nil
+
+	case "RawBlock":
+		var content []string
+		if err := json.Unmarshal(block.C, &content); err != nil || len(content) != 2 {
+			return "", fmt.Errorf("malformed RawBlock")
+		}
+		return content[1], nil
+
+	case "BlockQuote":
+		var blocks []pandocElement
+		if err := json.Unmarshal(block.C, &blocks); err != nil {
+			return "", err
+		}
+		text, err := r.blocks(blocks)
+		if err != nil {
+			return "", err
+		}
+		return prefixLines(text, "> "), nil
+
+	case "BulletList":
+		var items [][]pandocElement
+		if err := json.Unmarshal(block.C, &items); err != nil {
+			return "", err
+		}
+		return r.list(items, func(int) string { return "- " })
+
+	case "OrderedList":
+		var content []json.RawMessage
+		if err := json.Unmarshal(block.C, &content); err != nil || len(content) != 2 {
+			return "", fmt.Errorf("malformed OrderedList")
+		}
+		var attrs []json.RawMessage
+		start := 1
+		if err := json.Unmarshal(content[0], &attrs); err == nil && len(attrs) > 0 {
+			json.Unmarshal(attrs[0], &start)
+		}
+		var items [][]pandocElement
+		if err := json.Unmarshal(content[1], &items); err != nil {
+			return "", err
+		}
+		return r.list(items, func(i int) string { return fmt.Sprintf("%d. ", start+i) })
+
+	case "DefinitionList":
+		var items []json.RawMessage
+		if err := json.Unmarshal(block.C, &items); err != nil {
+			return "", err
+		}
+		rendered := []string{}
+		for _, item := range items {
+			var pair []json.RawMessage
+			if err := json.Unmarshal(item, &pair); err != nil || len(pair) != 2 {
+				return "", fmt.Errorf("malformed DefinitionList")
+			}
+			var term []pandocElement
+			var definitions [][]pandocElement
+			if err := json.Unmarshal(pair[0], &term); err != nil {
+				return "", err
+			}
+			if err := json.Unmarshal(pair[1], &definitions); err != nil {
+				return "", err
+			}
+			text, err := r.inlines(term)
+			if err != nil {
+				return "", err
+			}
+			defs, err := r.list(definitions, func(int) string { return "- " })
+			if err != nil {
+				return "", err
+			}
+			rendered = append(rendered, "**"+text+"**\n"+defs)
+		}
+		return strings.Join(rendered, "\n\n"), nil
+
+	case "Header":
+		var content []json.RawMessage
+		if err := json.Unmarshal(block.C, &content); err != nil || len(content) != 3 {
+			return "", fmt.Errorf("malformed Header")
+		}
+		var level int
+		var inlines []pandocElement
+		json.Unmarshal(content[0], &level)
+		if err := json.Unmarshal(content[2], &inlines); err != nil {
+			return "", err
+		}
+		text, err := r.inlines(inlines)
+		if err != nil {
+			return "", err
+		}
+		// The converter knows three heading levels
+		if level > 3 {
+			level = 3
+		}
+		return strings.Repeat("#", level) + " " + text, nil
+
+	case "HorizontalRule":
+		return "---", nil
+
+	case "Table":
+		return r.table(block.C)
+
+	case "Figure", "Div":
+		var content []json.RawMessage
+		if err := json.Unmarshal(block.C, &content); err != nil || len(content) < 2 {
+			return "", fmt.Errorf("malformed %s", block.T)
+		}
+		var blocks []pandocElement
+		if err := json.Unmarshal(content[len(content)-1], &blocks); err != nil {
+			return "", err
+		}
+		return r.blocks(blocks)
+	}
+
+	return "", nil
+}
+
+// list renders list items, indenting continuation lines under the marker
+func (r *pandocRenderer) list(items [][]pandocElement, marker func(int) string) (string, error) {
+	rendered := []string{}
+	for i, item := range items {
+		text, err := r.blocks(item)
+		if err != nil {
+			return "", err
+		}
+		// Tight lists: paragraphs within an item stay on consecutive lines
+		text = strings.ReplaceAll(text, "\n\n", "\n")
+		lines := strings.Split(text, "\n")
+		for j := 1; j < len(lines); j++ {
+			lines[j] = "  " + lines[j]
+		}
+		rendered = append(rendered, marker(i)+strings.Join(lines, "\n"))
+	}
+	return strings.Join(rendered, "\n"), nil
+}
+
+// table renders a pandoc 1.22+ table as a markdown pipe table
+func (r *pandocRenderer) table(raw json.RawMessage) (string, error) {
+	var content []json.RawMessage
+	if err := json.Unmarshal(raw, &content); err != nil || len(content) != 6 {
+		return "", fmt.Errorf("malformed Table")
+	}
+
+	// Rows are [Attr, [Cell]]; cells are [Attr, Alignment, RowSpan, ColSpan, [Block]]
+	rowsOf := func(raw json.RawMessage) ([][]string, error) {
+		var rows [][]json.RawMessage
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, err
+		}
+		result := [][]string{}
+		for _, row := range rows {
+			if len(row) != 2 {
+				return nil, fmt.Errorf("malformed table row")
+			}
+			var cells [][]json.RawMessage
+			if err := json.Unmarshal(row[1], &cells); err != nil {
+				return nil, err
+			}
+			rendered := []string{}
+			for _, cell := range cells {
+				if len(cell) != 5 {
+					return nil, fmt.Errorf("malformed table cell")
+				}
+				var blocks []pandocElement
+				if err := json.Unmarshal(cell[4], &blocks); err != nil {
+					return nil, err
+				}
+				text, err := r.blocks(blocks)
+				if err != nil {
+					return nil, err
+				}
+				text = strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", "\\|")
+				rendered = append(rendered, text)
+			}
+			result = append(result, rendered)
+		}
+		return result, nil
+	}
+
+	var head []json.RawMessage
+	if err := json.Unmarshal(content[3], &head); err != nil || len(head) != 2 {
+		return "", fmt.Errorf("malformed table head")
+	}
+	rows, err := rowsOf(head[1])
+	if err != nil {
+		return "", err
+	}
+
+	var bodies [][]json.RawMessage
+	if err := json.Unmarshal(content[4], &bodies); err != nil {
+		return "", err
+	}
+	for _, body := range bodies {
+		if len(body) != 4 {
+			return "", fmt.Errorf("malformed table body")
+		}
+		for _, part := range body[2:] {
+			bodyRows, err := rowsOf(part)
+			if err != nil {
+				return "", err
+			}
+			rows = append(rows, bodyRows...)
+		}
+	}
+
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	lines := []string{}
+	for i, row := range rows {
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat("---|", len(row)))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// inlines renders a list of inline elements
+func (r *pandocRenderer) inlines(inlines []pandocElement) (string, error) {
+	var b strings.Builder
+	for _, inline := range inlines {
+		text, err := r.inline(inline)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text)
+	}
+	return b.String(), nil
+}
+
+// inline renders a single inline element
+func (r *pandocRenderer) inline(inline pandocElement) (string, error) {
+	// Elements that wrap a plain list of inlines
+	wrappers := map[string][2]string{
+		"Emph":        {"*", "*"},
+		"Underline":   {"_", "_"},
+		"Strong":      {"**", "**"},
+		"Strikeout":   {"~~", "~~"},
+		"Superscript": {"^", "^"},
+		"Subscript":   {"~", "~"},
+		"SmallCaps":   {"", ""},
+	}
+	if wrap, ok := wrappers[inline.T]; ok {
+		var inlines []pandocElement
+		if err := json.Unmarshal(inline.C, &inlines); err != nil {
+			return "", err
+		}
+		text, err := r.inlines(inlines)
+		if err != nil {
+			return "", err
+		}
+		return wrap[0] + text + wrap[1], nil
+	}
+
+	switch inline.T {
+	case "Str":
+		var text string
+		err := json.Unmarshal(inline.C, &text)
+		return text, err
+
+	case "Space", "SoftBreak":
+		return " ", nil
+
+	case "LineBreak":
+		return "  \n", nil
+
+	case "Code", "Math", "RawInline":
+		var content []json.RawMessage
+		if err := json.Unmarshal(inline.C, &content); err != nil || len(content) != 2 {
+			return "", fmt.Errorf("malformed %s", inline.T)
+		}
+		var text string
+		if err := json.Unmarshal(content[1], &text); err != nil {
+			return "", err
+		}
+		if inline.T == "RawInline" {
+			return text, nil
+		}
+		return "`" + text + "`", nil
+
+	case "Quoted":
+		var content []json.RawMessage
+		if err := json.Unmarshal(inline.C, &content); err != nil || len(content) != 2 {
+			return "", fmt.Errorf("malformed Quoted")
+		}
+		var kind pandocElement
+		var inlines []pandocElement
+		json.Unmarshal(content[0], &kind)
+		if err := json.Unmarshal(content[1], &inlines); err != nil {
+			return "", err
+		}
+		text, err := r.inlines(inlines)
+		if err != nil {
+			return "", err
+		}
+		if kind.T == "SingleQuote" {
+			return "‘" + text + "’", nil
+		}
+		return "“" + text + "”", nil
+
+	case "Link", "Image":
+		var content []json.RawMessage
+		if err := json.Unmarshal(inline.C, &content); err != nil || len(content) != 3 {
+			return "", fmt.Errorf("malformed %s", inline.T)
+		}
+		var inlines []pandocElement
+		var target []string
+		if err := json.Unmarshal(content[1], &inlines); err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(content[2], &target); err != nil || len(target) != 2 {
+			return "", fmt.Errorf("malformed %s target", inline.T)
+		}
+		text, err := r.inlines(inlines)
+		if err != nil {
+			return "", err
+		}
+		if text == "" {
+			text = target[0]
+		}
+		return "[" + text + "](" + target[0] + ")", nil
+
+	case "Cite", "Span":
+		var content []json.RawMessage
+		if err := json.Unmarshal(inline.C, &content); err != nil || len(content) != 2 {
+			return "", fmt.Errorf("malformed %s", inline.T)
+		}
+		var inlines []pandocElement
+		if err := json.Unmarshal(content[1], &inlines); err != nil {
+			return "", err
+		}
+		return r.inlines(inlines)
+
+	case "Note":
+		var blocks []pandocElement
+		if err := json.Unmarshal(inline.C, &blocks); err != nil {
+			return "", err
+		}
+		text, err := r.blocks(blocks)
+		if err != nil {
+			return "", err
+		}
+		r.notes = append(r.notes, strings.ReplaceAll(text, "\n\n", " "))
+		return fmt.Sprintf("[%d]", len(r.notes)), nil
+	}
+
+	return "", nil
+}
+
+// prefixLines puts prefix in front of every line of text
+func prefixLines(text string, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,72 @@
+package slackify
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// secretRule is a kind of credential and the pattern that finds it
+type secretRule struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// builtinSecrets are the credentials found without any SecretPatterns
+var builtinSecrets = []secretRule{
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"AWS secret key", regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposre]-[0-9A-Za-z-]{10,}`)},
+	{"Slack webhook", regexp.MustCompile(`https://hooks\.slack\.com/services/[A-Za-z0-9/]+`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Stripe key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`)},
+}
+
+// secretRules returns the built-in credential patterns and the custom ones
+// in patterns. Custom patterns that do not compile are skipped.
+func secretRules(patterns []string) []secretRule {
+	rules := slices.Clone(builtinSecrets)
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			rules = append(rules, secretRule{"secret matching " + pattern, re})
+		}
+	}
+	return rules
+}
+
+// Secrets reports the credentials in text that look like tokens, keys or
+// opts.SecretPatterns, with their position in text
+func Secrets(text string, opts Options) []Warning {
+	var warnings []Warning
+	for _, rule := range secretRules(opts.SecretPatterns) {
+		for _, loc := range rule.pattern.FindAllStringIndex(text, -1) {
+			line, column := position(text, loc[0])
+			message := fmt.Sprintf("possible %s", rule.kind)
+			if opts.Redact {
+				message += " is masked"
+			}
+			warnings = append(warnings, Warning{WarnSecret, line, column, message})
+		}
+	}
+	slices.SortStableFunc(warnings, func(a, b Warning) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return warnings
+}
+
+// Redact masks the credentials Secrets finds in text
+func Redact(text string, opts Options) string {
+	return redactSecrets(text, secretRules(opts.SecretPatterns))
+}
+
+// redactSecrets replaces every match of rules with [REDACTED]
+func redactSecrets(text string, rules []secretRule) string {
+	for _, rule := range rules {
+		text = rule.pattern.ReplaceAllLiteralString(text, "[REDACTED]")
+	}
+	return text
+}
@@ -0,0 +1,542 @@
+package slackify
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ConvertToBlocks converts markdown text to Block Kit blocks. Use SplitBlocks
+// to post more than MaxBlocks of them.
+func ConvertToBlocks(text string, opts Options) ([]Block, error) {
+	return New(WithOptions(opts)).ConvertToBlocks(text)
+}
+
+// ConvertToBlocks converts markdown text to Block Kit blocks, ready to marshal
+// into a payload. Blocks Slack would reject are reported as BlockErrors; use
+// SplitBlocks to post more than MaxBlocks of them.
+func (c *Converter) ConvertToBlocks(text string) ([]Block, error) {
+	text, err := runStages(context.Background(), text, c.stages[:c.markdownStages], c.opts)
+	if err != nil {
+		return nil, err
+	}
+	text = mapOutsideCode(text, func(s string) string {
+		s = convertCallouts(s, c.opts.ASCII || c.opts.NoEmoji, "**")
+		return convertWikiLinks(s, c.opts.WikiURL)
+	})
+	if c.opts.NoEmoji {
+		text = mapOutsideCode(text, stripEmoji)
+	}
+
+	blocks := richTextBlocks(text)
+
+	// Catch what Slack would reject, with a path to the offending block
+	for _, message := range SplitBlocks(blocks, MaxBlocks) {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		if err := ValidateBlocks(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+// ConvertRichText converts markdown text to Block Kit payloads of rich_text
+// blocks, {"blocks": [...]}, which keep nested lists and quotes intact where
+// mrkdwn cannot. Long documents take several payloads, one per message.
+func ConvertRichText(text string, opts Options) ([]string, error) {
+	return New(WithOptions(opts)).ConvertRichText(text)
+}
+
+// ConvertRichText converts markdown text to Block Kit payloads, one per
+// message, each within Slack's limit of MaxBlocks blocks. Options.Index,
+// Options.MaxMessages, Options.PartNumbers and Options.Footer apply as they
+// do to ConvertMessages, the last two as context blocks.
+func (c *Converter) ConvertRichText(text string) ([]string, error) {
+	blocks, err := c.ConvertToBlocks(text)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := MaxBlocks
+	if c.opts.MaxMessages > 0 {
+		// Leave room for a truncation notice
+		limit--
+	}
+	if c.opts.PartNumbers {
+		// And for a context block with the part number
+		limit--
+	}
+	if c.opts.Footer != "" {
+		// And for one with the footer
+		limit--
+	}
+	messages := SplitBlocks(blocks, limit)
+	posted, indexed := c.postedMessages(len(messages))
+	if posted < len(messages) {
+		notice, err := c.ConvertToBlocks(truncationNotice(posted, len(messages), c.opts))
+		if err != nil {
+			return nil, err
+		}
+		messages[posted-1] = slices.Concat(messages[posted-1], notice)
+	}
+	for i := range messages[:posted] {
+		if c.opts.PartNumbers && posted > 1 {
+			messages[i] = slices.Concat([]Block{contextBlock("_" + partLabel(i+1, posted) + "_")}, messages[i])
+		}
+		if c.opts.Footer != "" {
+			messages[i] = slices.Concat(messages[i], []Block{contextBlock(footerLine(c.opts.Footer))})
+		}
+	}
+	payloads := make([]string, len(messages))
+	for i, message := range messages {
+		if payloads[i], err = payload(message); err != nil {
+			return nil, err
+		}
+	}
+
+	var index string
+	if indexed {
+		if index, err = c.richTextIndex(text, payloads, posted); err != nil {
+			return nil, err
+		}
+	}
+	payloads = payloads[:posted]
+	if index != "" {
+		payloads = append([]string{index}, payloads...)
+	}
+	return payloads, nil
+}
+
+// contextBlock returns a context block showing mrkdwn text
+func contextBlock(text string) Block {
+	return Block{Type: "context", Elements: []RichTextElement{{Type: "mrkdwn", Text: text}}}
+}
+
+// payload marshals blocks as a message payload, {"blocks": [...]}
+func payload(blocks []Block) (string, error) {
+	data, err := json.MarshalIndent(struct {
+		Blocks []Block `json:"blocks"`
+	}{blocks}, "", "  ")
+	return string(data), err
+}
+
+// SplitBlocks groups blocks into messages of at most limit blocks. A message
+// ends at the last divider in its second half when there is one, so sections
+// stay together, and dividers at the edges of a message are dropped.
+func SplitBlocks(blocks []Block, limit int) [][]Block {
+	var messages [][]Block
+	for len(blocks) > 0 {
+		for len(blocks) > 0 && blocks[0].Type == "divider" {
+			blocks = blocks[1:]
+		}
+		if len(blocks) == 0 {
+			break
+		}
+
+		end := min(limit, len(blocks))
+		if end < len(blocks) && blocks[end].Type != "divider" {
+			for i := end - 1; i > limit/2; i-- {
+				if blocks[i].Type == "divider" {
+					end = i
+					break
+				}
+			}
+		}
+
+		message := blocks[:end]
+		for len(message) > 0 && message[len(message)-1].Type == "divider" {
+			message = message[:len(message)-1]
+		}
+		messages = append(messages, message)
+		blocks = blocks[end:]
+	}
+	if len(messages) == 0 {
+		messages = append(messages, []Block{})
+	}
+	return messages
+}
+
+// imageBlock returns an image block for an image on a line of its own. Slack
+// only fetches https images, so others become a section linking to the image.
+func imageBlock(url, alt, title string) Block {
+	if alt == "" {
+		alt = "image"
+	}
+	if !strings.HasPrefix(url, "https://") {
+		return Block{Type: "section", Text: &TextObject{"mrkdwn", "<" + url + "|" + alt + ">"}}
+	}
+
+	b := Block{Type: "image", ImageURL: url, AltText: alt}
+	if title != "" {
+		b.Title = &TextObject{"plain_text", title}
+	}
+	return b
+}
+
+// headerBlock returns a header block for heading, a top-level heading whose
+// title is title, with the title's markup flattened to plain text and cut to
+// Slack's limit of headerTextLimit characters. Other headings, and those
+// left without text, have none.
+func headerBlock(heading, title string) (Block, bool) {
+	if !strings.HasPrefix(heading, "# ") && !strings.HasPrefix(heading, "#\t") {
+		return Block{}, false
+	}
+
+	var b strings.Builder
+	for _, e := range richInline(title, TextStyle{}) {
+		switch {
+		case e.Text != "":
+			b.WriteString(e.Text)
+		case e.Type == "emoji":
+			b.WriteString(":" + e.Name + ":")
+		case e.URL != "":
+			b.WriteString(e.URL)
+		}
+	}
+	text := strings.Join(strings.Fields(b.String()), " ")
+	if text == "" {
+		return Block{}, false
+	}
+	if runes := []rune(text); len(runes) > headerTextLimit {
+		text = strings.TrimRightFunc(string(runes[:headerTextLimit-1]), unicode.IsSpace) + "\u2026"
+	}
+	return Block{Type: "header", Text: &TextObject{"plain_text", text}}, true
+}
+
+// Patterns for the block syntax rich text supports
+var (
+	richHeadingRegex  = regexp.MustCompile(`^#{1,6}[ \t]+(.*?)[ \t#]*$`)
+	ruleRegex         = regexp.MustCompile(`^([-*_]\s*){3,}$`)
+	richListItemRegex = regexp.MustCompile(`(?s)^(\s*)([-*+]|(\d+)[.)])[ \t]+(.*)$`)
+	richImageRegex    = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)(?:\s+"([^"]*)")?\)$`)
+)
+
+// richTextBlocks parses markdown into rich_text blocks, one per run of content
+// between horizontal rules, which become dividers, and top-level headings,
+// which become header blocks
+func richTextBlocks(text string) []Block {
+	var blocks []Block
+	var elements []RichTextElement
+	var paragraph []string
+
+	// section adds inline elements, joining them to a section right before
+	section := func(inline []RichTextElement) {
+		if n := len(elements); n > 0 && elements[n-1].Type == "rich_text_section" {
+			elements[n-1].Elements = append(elements[n-1].Elements, RichTextElement{Type: "text", Text: "\n\n"})
+			elements[n-1].Elements = append(elements[n-1].Elements, inline...)
+			return
+		}
+		elements = append(elements, RichTextElement{Type: "rich_text_section", Elements: inline})
+	}
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			section(richInline(strings.Join(paragraph, "\n"), TextStyle{}))
+			paragraph = nil
+		}
+	}
+	flushBlock := func() {
+		flushParagraph()
+		if len(elements) > 0 {
+			blocks = append(blocks, Block{Type: "rich_text", Elements: elements})
+			elements = nil
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flushParagraph()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			elements = append(elements, preformatted(strings.Join(code, "\n")))
+
+		case ruleRegex.MatchString(trimmed):
+			flushBlock()
+			blocks = append(blocks, Block{Type: "divider"})
+
+		case richHeadingRegex.MatchString(trimmed):
+			title := richHeadingRegex.FindStringSubmatch(trimmed)[1]
+			if header, ok := headerBlock(trimmed, title); ok {
+				flushBlock()
+				blocks = append(blocks, header)
+				break
+			}
+			flushParagraph()
+			section(richInline(title, TextStyle{Bold: true}))
+
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(quoted, " "))
+			}
+			i--
+			elements = append(elements, RichTextElement{Type: "rich_text_quote", Elements: richInline(strings.Join(quote, "\n"), TextStyle{})})
+
+		case i+1 < len(lines) && ((strings.HasPrefix(trimmed, "|") && strings.Contains(lines[i+1], "-")) || isTableStart(trimmed, lines[i+1])):
+			flushParagraph()
+			var table []string
+			for ; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				table = append(table, strings.TrimSpace(lines[i]))
+			}
+			i--
+			elements = append(elements, preformatted(formatTableForSlack(table, Options{TableMode: "plain"})))
+
+		case richListItemRegex.MatchString(line) && len(paragraph) == 0:
+			var items []string
+			for ; i < len(lines); i++ {
+				if richListItemRegex.MatchString(lines[i]) {
+					items = append(items, lines[i])
+				} else if strings.TrimSpace(lines[i]) != "" && len(items) > 0 && (lines[i][0] == ' ' || lines[i][0] == '\t') {
+					// An indented continuation of the item above
+					items[len(items)-1] += "\n" + strings.TrimSpace(lines[i])
+				} else {
+					break
+				}
+			}
+			i--
+			elements = append(elements, richTextLists(items, richListItemRegex)...)
+
+		case richImageRegex.MatchString(trimmed):
+			flushBlock()
+			m := richImageRegex.FindStringSubmatch(trimmed)
+			blocks = append(blocks, imageBlock(m[2], m[1], m[3]))
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flushBlock()
+
+	return blocks
+}
+
+// preformatted returns a rich_text_preformatted element for code
+func preformatted(code string) RichTextElement {
+	if code == "" {
+		code = " "
+	}
+	return RichTextElement{Type: "rich_text_preformatted", Elements: []RichTextElement{{Type: "text", Text: code}}}
+}
+
+// richTextLists turns list item lines into rich_text_list elements; Slack
+// lists are flat, so each run of items at one indentation and style becomes
+// its own list with an indent level
+func richTextLists(items []string, itemRegex *regexp.Regexp) []RichTextElement {
+	var lists []RichTextElement
+	var indents []int
+	for _, item := range items {
+		parts := itemRegex.FindStringSubmatch(item)
+		width := len(strings.ReplaceAll(parts[1], "\t", "    "))
+
+		// Nesting level: how many shallower item indentations enclose this one
+		for len(indents) > 0 && indents[len(indents)-1] > width {
+			indents = indents[:len(indents)-1]
+		}
+		if len(indents) == 0 || indents[len(indents)-1] < width {
+			indents = append(indents, width)
+		}
+		level := len(indents) - 1
+
+		style, offset := "bullet", 0
+		if parts[3] != "" {
+			style = "ordered"
+			offset, _ = strconv.Atoi(parts[3])
+			offset--
+		}
+
+		element := RichTextElement{Type: "rich_text_section", Elements: richInline(parts[4], TextStyle{})}
+		if n := len(lists); n > 0 && lists[n-1].ListStyle == style && lists[n-1].Indent == level {
+			lists[n-1].Elements = append(lists[n-1].Elements, element)
+			continue
+		}
+		list := RichTextElement{Type: "rich_text_list", ListStyle: style, Indent: level, Elements: []RichTextElement{element}}
+		if style == "ordered" {
+			list.Offset = max(offset, 0)
+		}
+		lists = append(lists, list)
+	}
+	return lists
+}
+
+// Patterns for the inline syntax rich text supports, anchored at the
+// current position
+var (
+	richLinkRegex     = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+	richAutolinkRegex = regexp.MustCompile(`^<((?:https?://|mailto:)[^>|\s]+)(?:\|([^>\n]+))?>`)
+	richMentionRegex  = regexp.MustCompile(`^<([@#]|!subteam\^|!)([^<>|\s]+)(?:\|[^<>\n]*)?>`)
+	richEmojiRegex    = regexp.MustCompile(`^:([a-z0-9][a-z0-9_+'-]*|\+1):`)
+)
+
+// richInline parses inline markdown into text and link elements: code spans,
+// **bold**, *italic* or _italic_, ~~strike~~, [links](url), <autolinks> and
+// inline images, which become links
+func richInline(text string, style TextStyle) []RichTextElement {
+	var elements []RichTextElement
+	var plain strings.Builder
+	add := func(e RichTextElement) {
+		if n := len(elements); n > 0 && e.Type == "text" && elements[n-1].Type == "text" && *elements[n-1].TextStyle == *e.TextStyle {
+			elements[n-1].Text += e.Text
+			return
+		}
+		elements = append(elements, e)
+	}
+	flush := func() {
+		if plain.Len() > 0 {
+			s := style
+			add(RichTextElement{Type: "text", Text: plain.String(), TextStyle: &s})
+			plain.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		if rest[0] == '`' {
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				flush()
+				s := style
+				s.Code = true
+				add(RichTextElement{Type: "text", Text: rest[1 : end+1], TextStyle: &s})
+				i += end + 2
+				continue
+			}
+		}
+
+		// Images inside text can only be linked
+		if strings.HasPrefix(rest, "![") {
+			if m := richLinkRegex.FindStringSubmatch(rest[1:]); m != nil {
+				flush()
+				s := style
+				elements = append(elements, RichTextElement{Type: "link", URL: m[2], Text: m[1], TextStyle: &s})
+				i += 1 + len(m[0])
+				continue
+			}
+		}
+
+		if m := richLinkRegex.FindStringSubmatch(rest); m != nil {
+			flush()
+			s := style
+			elements = append(elements, RichTextElement{Type: "link", URL: m[2], Text: m[1], TextStyle: &s})
+			i += len(m[0])
+			continue
+		}
+		if m := richAutolinkRegex.FindStringSubmatch(rest); m != nil {
+			flush()
+			s := style
+			elements = append(elements, RichTextElement{Type: "link", URL: m[1], Text: m[2], TextStyle: &s})
+			i += len(m[0])
+			continue
+		}
+
+		// Slack markup in the input becomes the matching element
+		if m := richMentionRegex.FindStringSubmatch(rest); m != nil {
+			var e RichTextElement
+			switch m[1] {
+			case "@":
+				e = RichTextElement{Type: "user", UserID: m[2]}
+			case "#":
+				e = RichTextElement{Type: "channel", ChannelID: m[2]}
+			case "!subteam^":
+				e = RichTextElement{Type: "usergroup", UsergroupID: m[2]}
+			default:
+				if m[2] == "here" || m[2] == "channel" || m[2] == "everyone" {
+					e = RichTextElement{Type: "broadcast", Range: m[2]}
+				}
+			}
+			if e.Type != "" {
+				flush()
+				elements = append(elements, e)
+				i += len(m[0])
+				continue
+			}
+		}
+		if m := richEmojiRegex.FindStringSubmatch(rest); m != nil && (i == 0 || !isWordByte(text[i-1])) && (len(m[0]) == len(rest) || !isWordByte(rest[len(m[0])])) {
+			flush()
+			elements = append(elements, RichTextElement{Type: "emoji", Name: m[1]})
+			i += len(m[0])
+			continue
+		}
+
+		if delim, inner, ok := emphasis(rest, i == 0 || !isWordByte(text[i-1])); ok {
+			flush()
+			s := style
+			switch delim {
+			case "**", "__":
+				s.Bold = true
+			case "~~":
+				s.Strike = true
+			default:
+				s.Italic = true
+			}
+			for _, e := range richInline(inner, s) {
+				if e.Type == "text" {
+					add(e)
+				} else {
+					elements = append(elements, e)
+				}
+			}
+			i += len(inner) + 2*len(delim)
+			continue
+		}
+
+		plain.WriteByte(rest[0])
+		i++
+	}
+	flush()
+
+	return elements
+}
+
+// emphasis matches an emphasis span at the start of s, returning its
+// delimiter and content. Underscores only open a span at a word boundary.
+func emphasis(s string, atBoundary bool) (string, string, bool) {
+	for _, delim := range []string{"**", "__", "~~", "*", "_"} {
+		if !strings.HasPrefix(s, delim) || (delim[0] == '_' && !atBoundary) {
+			continue
+		}
+		body := s[len(delim):]
+		if body == "" || body[0] == ' ' || strings.HasPrefix(body, delim[:1]) {
+			continue
+		}
+		end := strings.Index(body, delim)
+		if len(delim) == 1 {
+			// A single delimiter must not close on half of a double one
+			for end >= 0 && end+1 < len(body) && body[end+1] == delim[0] {
+				next := strings.Index(body[end+2:], delim)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 2 + next
+			}
+		}
+		if end <= 0 || body[end-1] == ' ' || strings.Contains(body[:end], "\n\n") {
+			continue
+		}
+		return delim, body[:end], true
+	}
+	return "", "", false
+}
+
+// isWordByte reports whether c is an ASCII letter or digit
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
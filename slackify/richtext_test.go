@@ -0,0 +1,59 @@
+package slackify
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHeaderBlock(t *testing.T) {
+	tests := []struct {
+		heading string
+		want    string
+		ok      bool
+	}{
+		{"# Release notes", "Release notes", true},
+		{"# Release **1.2** of [tool](https://example.com)", "Release 1.2 of tool", true},
+		{"# Launch :tada:", "Launch :tada:", true},
+		{"## Details", "", false},
+		{"#", "", false},
+	}
+	for _, tt := range tests {
+		title := ""
+		if m := richHeadingRegex.FindStringSubmatch(tt.heading); m != nil {
+			title = m[1]
+		}
+		b, ok := headerBlock(tt.heading, title)
+		if ok != tt.ok || ok && (b.Type != "header" || b.Text.Type != "plain_text" || b.Text.Text != tt.want) {
+			t.Errorf("headerBlock(%q) = %+v, %v, want %q, %v", tt.heading, b.Text, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHeaderBlockLimit(t *testing.T) {
+	title := strings.Repeat("word ", 50)
+	b, ok := headerBlock("# "+title, title)
+	if !ok {
+		t.Fatal("headerBlock returned no block for a long heading")
+	}
+	if n := utf8.RuneCountInString(b.Text.Text); n > headerTextLimit {
+		t.Errorf("header text is %d characters, over the limit of %d", n, headerTextLimit)
+	}
+	if !strings.HasSuffix(b.Text.Text, "…") {
+		t.Errorf("header text %q does not end in an ellipsis", b.Text.Text)
+	}
+}
+
+func TestConvertToBlocksHeadersAndDividers(t *testing.T) {
+	blocks, err := ConvertToBlocks("# Title\n\nText\n\n---\n\n## Section\n\nMore", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, b := range blocks {
+		types = append(types, b.Type)
+	}
+	if got, want := strings.Join(types, ","), "header,rich_text,divider,rich_text"; got != want {
+		t.Errorf("block types = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package slackify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SourceEventType is the event type of the metadata SourceMetadata returns
+const SourceEventType = "slackify_source"
+
+// Metadata is Slack message metadata, which chat.postMessage takes along with
+// a message's text and blocks without showing it
+type Metadata struct {
+	EventType    string `json:"event_type"`
+	EventPayload Source `json:"event_payload"`
+}
+
+// Source tells where a message came from: the path or URL of the document,
+// its format, the SHA-256 of its text and, when embedded, the text itself
+type Source struct {
+	Path   string `json:"path,omitempty"`
+	Format string `json:"format,omitempty"`
+	SHA256 string `json:"sha256"`
+	Text   string `json:"text,omitempty"`
+}
+
+// SourceMetadata returns the metadata to post with a message converted from
+// text, so that a later edit can find the document it came from. With embed
+// the text itself is carried too, and the message can be converted again
+// without the document.
+func SourceMetadata(text, path, format string, embed bool) Metadata {
+	sum := sha256.Sum256([]byte(text))
+	source := Source{Path: path, Format: format, SHA256: hex.EncodeToString(sum[:])}
+	if embed {
+		source.Text = text
+	}
+	return Metadata{EventType: SourceEventType, EventPayload: source}
+}
+
+// Matches tells whether text is the document the metadata was made from
+func (m Metadata) Matches(text string) bool {
+	sum := sha256.Sum256([]byte(text))
+	return m.EventPayload.SHA256 == hex.EncodeToString(sum[:])
+}
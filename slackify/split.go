@@ -0,0 +1,115 @@
+package slackify
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Slack message lengths in characters: MessageLimit is what Slack recommends
+// keeping a message under, and longer text is cut off at MessageHardLimit
+const (
+	MessageLimit     = 4000
+	MessageHardLimit = 40000
+)
+
+// Split breaks mrkdwn text into messages of at most limit characters. It
+// breaks between blocks where it can, then between lines, and only cuts a
+// line when that alone is too long. A code block split across messages is
+// closed and reopened so each part renders as code.
+func Split(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var messages []string
+	current := ""
+	for _, block := range textBlocks(text) {
+		for _, part := range splitBlock(block, limit) {
+			switch {
+			case current == "":
+				current = part
+			case utf8.RuneCountInString(current)+2+utf8.RuneCountInString(part) <= limit:
+				current += "\n\n" + part
+			default:
+				messages = append(messages, current)
+				current = part
+			}
+		}
+	}
+	if current != "" {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// textBlocks splits text at blank lines outside code blocks
+func textBlocks(text string) []string {
+	var blocks []string
+	var current []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Count(line, "```")%2 == 1 {
+			inCode = !inCode
+		}
+		if strings.TrimSpace(line) == "" && !inCode {
+			if len(current) > 0 {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// splitBlock breaks one block into parts of at most limit characters
+func splitBlock(block string, limit int) []string {
+	if utf8.RuneCountInString(block) <= limit {
+		return []string{block}
+	}
+
+	fence := ""
+	if strings.HasPrefix(block, "```") && strings.HasSuffix(block, "```") && len(block) >= 6 {
+		fence = "```"
+		block = block[3 : len(block)-3]
+		limit = max(limit-6, 1)
+	}
+
+	var parts []string
+	current := ""
+	add := func(line string) {
+		switch {
+		case current == "":
+			current = line
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(line) <= limit:
+			current += "\n" + line
+		default:
+			parts = append(parts, fence+current+fence)
+			current = line
+		}
+	}
+	for _, line := range strings.Split(block, "\n") {
+		for utf8.RuneCountInString(line) > limit {
+			cut := runeOffset(line, limit)
+			add(line[:cut])
+			line = line[cut:]
+		}
+		add(line)
+	}
+	return append(parts, fence+current+fence)
+}
+
+// runeOffset returns the byte offset of the n-th character of s
+func runeOffset(s string, n int) int {
+	for offset := range s {
+		if n == 0 {
+			return offset
+		}
+		n--
+	}
+	return len(s)
+}
@@ -0,0 +1,516 @@
+package slackify
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TableFormatter handles markdown table conversion
+type TableFormatter struct {
+	lines []string
+}
+
+// convertTables converts markdown tables to Slack-friendly format
+func convertTables(text string, opts Options) string {
+	lines := strings.Split(text, "\n")
+	result := []string{}
+	i := 0
+
+	for i < len(lines) {
+		line := strings.TrimSpace(lines[i])
+
+		// Check if this line looks like a table header, with or without
+		// outer pipes
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+		if (strings.Contains(line, "|") && strings.HasPrefix(line, "|") && strings.HasSuffix(line, "|")) || isTableStart(line, next) {
+			// Found potential table start
+			tableLines := []string{}
+			j := i
+
+			// Collect all table lines
+			for j < len(lines) {
+				currentLine := strings.TrimSpace(lines[j])
+				if strings.Contains(currentLine, "|") && (strings.HasPrefix(currentLine, "|") || strings.Contains(currentLine, "|")) {
+					tableLines = append(tableLines, currentLine)
+					j++
+				} else if currentLine == "" {
+					// Empty line might be part of table formatting, unless
+					// spacing is preserved, in which case it ends the table
+					if !opts.PreserveSpacing && j+1 < len(lines) && continuesTable(lines[j+1:]) {
+						tableLines = append(tableLines, currentLine)
+						j++
+					} else {
+						break
+					}
+				} else {
+					break
+				}
+			}
+
+			if len(tableLines) >= 2 { // At least header + separator
+				// Convert table to formatted text
+				formattedTable := formatTableForSlack(tableLines, opts)
+				result = append(result, formattedTable)
+				i = j
+				continue
+			}
+		}
+
+		// Keep indentation; only trailing whitespace goes unless spacing is preserved
+		if opts.PreserveSpacing {
+			result = append(result, lines[i])
+		} else {
+			result = append(result, strings.TrimRightFunc(lines[i], unicode.IsSpace))
+		}
+		i++
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// cellBreakRegex finds <br> line breaks in table cells
+var cellBreakRegex = regexp.MustCompile(`(?i)\s*<br\s*/?>\s*`)
+
+// formatTableForSlack formats a markdown table for Slack display, inside a
+// code block unless opts.TableMode is "plain". Right-to-left cells end in a
+// left-to-right mark, or are wrapped in directional isolates with
+// opts.TableIsolates, so bidi reordering cannot move them across column
+// separators. Cells wider than opts.MaxCellWidth are cut short.
+func formatTableForSlack(tableLines []string, opts Options) string {
+	// Remove empty lines and clean up
+	cleanLines := []string{}
+	for _, line := range tableLines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" {
+			cleanLines = append(cleanLines, trimmed)
+		}
+	}
+
+	if len(cleanLines) < 2 {
+		return strings.Join(tableLines, "\n") // Return as-is if not a proper table
+	}
+
+	// Parse table
+	rows := [][]string{}
+	for _, line := range cleanLines {
+		// Skip separator lines (|---|---|)
+		if isTableDelimiter(line) {
+			continue
+		}
+
+		if cells := splitTableRow(line); len(cells) > 0 {
+			// <br> breaks a cell into lines
+			for j, cell := range cells {
+				cells[j] = cellBreakRegex.ReplaceAllString(cell, "\n")
+			}
+			rows = append(rows, cells)
+		}
+	}
+
+	if len(rows) == 0 {
+		return strings.Join(tableLines, "\n")
+	}
+
+	if opts.TableSort != "" {
+		sortRows(rows, opts.TableSort)
+	}
+	more := 0
+	if opts.TableLimit > 0 && len(rows)-1 > opts.TableLimit {
+		more = len(rows) - 1 - opts.TableLimit
+		rows = rows[:opts.TableLimit+1]
+	}
+	if len(opts.TableColumns) > 0 {
+		rows = selectColumns(rows, opts.TableColumns)
+	}
+
+	table := renderTable(rows, opts)
+	switch {
+	case more == 1:
+		table += "\n" + ellipsis(opts) + "and 1 more row"
+	case more > 1:
+		table += fmt.Sprintf("\n%sand %d more rows", ellipsis(opts), more)
+	}
+	return table
+}
+
+// sortRows sorts the body rows, after the header, by column: a header or
+// 1-based index, descending when it starts with -. Cells that are both
+// numbers compare as numbers, ignoring currency signs, percent signs and
+// thousands separators, and come before the rest, which compare as text
+// ignoring case. A column the table lacks leaves it unsorted.
+func sortRows(rows [][]string, column string) {
+	name, descending := strings.CutPrefix(strings.TrimSpace(column), "-")
+	col := tableColumn(rows[0], name)
+	if col < 0 {
+		return
+	}
+
+	number := func(cell string) (float64, bool) {
+		cell = strings.NewReplacer(",", "", "$", "", "€", "", "£", "", "%", "").Replace(strings.TrimSpace(cell))
+		n, err := strconv.ParseFloat(cell, 64)
+		return n, err == nil
+	}
+	cell := func(row []string) string {
+		if col < len(row) {
+			return row[col]
+		}
+		return ""
+	}
+
+	slices.SortStableFunc(rows[1:], func(a, b []string) int {
+		x, y := cell(a), cell(b)
+		m, xNumber := number(x)
+		n, yNumber := number(y)
+		result := 0
+		switch {
+		case xNumber && yNumber:
+			result = cmp.Compare(m, n)
+		case xNumber != yNumber:
+			// Text sorts after numbers either way
+			if xNumber {
+				return -1
+			}
+			return 1
+		default:
+			result = cmp.Compare(strings.ToLower(x), strings.ToLower(y))
+		}
+		if descending {
+			return -result
+		}
+		return result
+	})
+}
+
+// tableColumn returns the index of column in a table with header, given by
+// header text, ignoring case, or 1-based index, or -1 when the table has no
+// such column
+func tableColumn(header []string, column string) int {
+	if n, err := strconv.Atoi(column); err == nil {
+		if n >= 1 && n <= len(header) {
+			return n - 1
+		}
+		return -1
+	}
+	return slices.IndexFunc(header, func(h string) bool {
+		return strings.EqualFold(strings.TrimSpace(h), column)
+	})
+}
+
+// selectColumns picks the columns of rows named in columns, by header or
+// 1-based index, in that order. Columns given as -name or -index are left
+// out instead, and when only those are given all other columns stay. A
+// table without any of the chosen columns is left whole.
+func selectColumns(rows [][]string, columns []string) [][]string {
+	var keep []int
+	excluded := map[int]bool{}
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		if name, ok := strings.CutPrefix(column, "-"); ok {
+			if i := tableColumn(rows[0], name); i >= 0 {
+				excluded[i] = true
+			}
+		} else if i := tableColumn(rows[0], column); i >= 0 {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) == 0 && len(excluded) > 0 {
+		for i := range rows[0] {
+			keep = append(keep, i)
+		}
+	}
+	keep = slices.DeleteFunc(keep, func(i int) bool { return excluded[i] })
+	if len(keep) == 0 {
+		return rows
+	}
+
+	selected := make([][]string, len(rows))
+	for r, row := range rows {
+		for _, i := range keep {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			selected[r] = append(selected[r], cell)
+		}
+	}
+	return selected
+}
+
+// renderTable lays out parsed rows, the first being the header, as aligned
+// text. A newline in a cell starts another line within its row.
+func renderTable(rows [][]string, opts Options) string {
+	var footnotes []string
+	if opts.MaxCellWidth > 0 {
+		for _, row := range rows {
+			for j, cell := range row {
+				lines := strings.Split(cell, "\n")
+				for k, line := range lines {
+					if displayWidth(line) <= opts.MaxCellWidth {
+						continue
+					}
+					if opts.CellFootnotes {
+						footnotes = append(footnotes, fmt.Sprintf("[%d] %s", len(footnotes)+1, line))
+						lines[k] = truncateCell(line, opts.MaxCellWidth, fmt.Sprintf("%s[%d]", ellipsis(opts), len(footnotes)))
+					} else {
+						lines[k] = truncateCell(line, opts.MaxCellWidth, ellipsis(opts))
+					}
+				}
+				row[j] = strings.Join(lines, "\n")
+			}
+		}
+	}
+
+	// Calculate column widths
+	maxCols := 0
+	for _, row := range rows {
+		if len(row) > maxCols {
+			maxCols = len(row)
+		}
+	}
+
+	colWidths := make([]int, maxCols)
+	for _, row := range rows {
+		for col, cell := range row {
+			for _, line := range strings.Split(cell, "\n") {
+				colWidths[col] = max(colWidths[col], displayWidth(line))
+			}
+		}
+	}
+
+	// Format as code block for better alignment
+	result := []string{"```"}
+
+	for i, row := range rows {
+		cells := make([][]string, len(row))
+		height := 1
+		for j, cell := range row {
+			cells[j] = strings.Split(cell, "\n")
+			height = max(height, len(cells[j]))
+		}
+
+		for k := 0; k < height; k++ {
+			formattedRow := []string{}
+			for j, lines := range cells {
+				cell := ""
+				if k < len(lines) {
+					cell = lines[k]
+				}
+				padding := strings.Repeat(" ", colWidths[j]-displayWidth(cell))
+				switch {
+				case !isRTL(cell):
+				case opts.TableIsolates:
+					cell = "\u2068" + cell + "\u2069"
+				default:
+					cell += "\u200e"
+				}
+				formattedRow = append(formattedRow, cell+padding)
+			}
+
+			line := strings.Join(formattedRow, " | ")
+			if isRTL(line) && !opts.TableIsolates {
+				// Keep the row left to right when it starts with a right-to-left cell
+				line = "\u200e" + line
+			}
+			result = append(result, line)
+		}
+
+		// Add separator after header
+		if i == 0 {
+			separator := []string{}
+			for _, width := range colWidths {
+				separator = append(separator, strings.Repeat("-", width))
+			}
+			result = append(result, strings.Join(separator, "-|-"))
+		}
+	}
+
+	result = append(result, "```")
+	if opts.TableMode == "plain" {
+		result = result[1 : len(result)-1]
+	}
+	if len(footnotes) > 0 {
+		result = append(result, "")
+		result = append(result, footnotes...)
+	}
+	return strings.Join(result, "\n")
+}
+
+// gridBorderRegex finds the border lines of grid tables
+var gridBorderRegex = regexp.MustCompile(`^\+([-=:]+\+)+$`)
+
+// convertGridTables turns pandoc grid tables, drawn with +---+ borders and
+// cells that may span several lines, into pipe tables. The lines of a cell
+// are joined with <br>, so they stay separate lines in the rendered table.
+// The first row is the header, whether or not a +===+ border marks it.
+func convertGridTables(text string) string {
+	lines := strings.Split(text, "\n")
+	var result []string
+	for i := 0; i < len(lines); i++ {
+		border := strings.TrimSpace(lines[i])
+		if !gridBorderRegex.MatchString(border) || i+1 >= len(lines) || !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "|") {
+			result = append(result, lines[i])
+			continue
+		}
+
+		// Column edges are where the first border has a +
+		var edges []int
+		for col, r := range []rune(border) {
+			if r == '+' {
+				edges = append(edges, col)
+			}
+		}
+
+		var rows [][]string
+		cells := make([][]string, len(edges)-1)
+		j := i + 1
+		for ; j < len(lines); j++ {
+			line := strings.TrimSpace(lines[j])
+			if gridBorderRegex.MatchString(line) {
+				row := make([]string, len(cells))
+				for k, parts := range cells {
+					row[k] = strings.ReplaceAll(joinGridCell(parts), "|", "\\|")
+				}
+				rows = append(rows, row)
+				cells = make([][]string, len(edges)-1)
+				continue
+			}
+			if !strings.HasPrefix(line, "|") {
+				break
+			}
+			for k, part := range gridCells(line, edges) {
+				cells[k] = append(cells[k], part)
+			}
+		}
+
+		if len(rows) == 0 {
+			result = append(result, lines[i])
+			continue
+		}
+		for k, row := range rows {
+			result = append(result, "| "+strings.Join(row, " | ")+" |")
+			if k == 0 {
+				result = append(result, "|"+strings.Repeat("---|", len(row)))
+			}
+		}
+		i = j - 1
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// joinGridCell joins the lines of a grid table cell with <br>, keeping one
+// blank line where blank lines separate them and dropping those around them
+func joinGridCell(parts []string) string {
+	var lines []string
+	blank := false
+	for _, part := range parts {
+		switch {
+		case part == "":
+			blank = len(lines) > 0
+		case blank:
+			lines = append(lines, "", part)
+			blank = false
+		default:
+			lines = append(lines, part)
+		}
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// gridCells cuts a grid table content line into its trimmed cells at the
+// display columns of the border's edges
+func gridCells(line string, edges []int) []string {
+	cells := make([]string, len(edges)-1)
+	col := 0
+	for _, r := range line {
+		for k := range cells {
+			if col > edges[k] && col < edges[k+1] {
+				cells[k] += string(r)
+			}
+		}
+		col += max(displayWidth(string(r)), 1)
+	}
+	for k := range cells {
+		cells[k] = strings.TrimSpace(cells[k])
+	}
+	return cells
+}
+
+// tableDelimiterRegex finds the delimiter row under a table header
+var tableDelimiterRegex = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// isTableDelimiter reports whether line is a table's delimiter row, like
+// |---|:--:| or --- | ---
+func isTableDelimiter(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.Contains(line, "|") && tableDelimiterRegex.MatchString(line)
+}
+
+// isTableStart reports whether line is the header row of a table whose
+// delimiter row is next. Either may leave out the outer pipes.
+func isTableStart(line, next string) bool {
+	return strings.Contains(line, "|") && !isTableDelimiter(line) && isTableDelimiter(next)
+}
+
+// continuesTable reports whether the rows after a blank line inside a table
+// still belong to it: they need outer pipes and must not start a new table
+func continuesTable(rest []string) bool {
+	next := ""
+	if len(rest) > 1 {
+		next = rest[1]
+	}
+	return strings.HasPrefix(strings.TrimSpace(rest[0]), "|") && !isTableStart(rest[0], next)
+}
+
+// splitTableRow splits a table row into its trimmed cells. The outer pipes
+// are optional, and \| is a pipe inside a cell.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// truncateCell cuts cell down so that, with suffix added, it is at most
+// width columns wide
+func truncateCell(cell string, width int, suffix string) string {
+	runes := []rune(cell)
+	for n := len(runes) - 1; n > 0; n-- {
+		if cut := strings.TrimRightFunc(string(runes[:n]), unicode.IsSpace) + suffix; displayWidth(cut) <= width {
+			return cut
+		}
+	}
+	return suffix
+}
+
+// isRTL reports whether text contains characters of a right-to-left script
+func isRTL(text string) bool {
+	return strings.ContainsFunc(text, func(r rune) bool {
+		return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+	})
+}
@@ -0,0 +1,189 @@
+package slackify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertTables(t *testing.T) {
+	const fruit = "| Name | Qty |\n|---|---:|\n| fig | 12 |\n| Apple | 3 |\n| cherry | 1,200 |"
+
+	tests := []struct {
+		name string
+		in   string
+		opts Options
+		want string
+	}{
+		{
+			name: "pipe table",
+			in:   fruit,
+			want: "```\nName   | Qty  \n-------|------\nfig    | 12   \nApple  | 3    \ncherry | 1,200\n```",
+		},
+		{
+			name: "plain mode",
+			in:   "| A | B |\n|---|---|\n| 1 | 2 |",
+			opts: Options{TableMode: "plain"},
+			want: "A | B\n--|--\n1 | 2",
+		},
+		{
+			name: "without outer pipes",
+			in:   "Name | Qty\n--- | ---\napple | 3",
+			want: "```\nName  | Qty\n------|----\napple | 3  \n```",
+		},
+		{
+			name: "br in cells",
+			in:   "| A | B |\n|---|---|\n| one<br>two | x |",
+			want: "```\nA   | B\n----|--\none | x\ntwo |  \n```",
+		},
+		{
+			name: "emoji width",
+			in:   "| Name | Flag |\n|---|---|\n| 🎉 | x |\n| ab | y |",
+			want: "```\nName | Flag\n-----|-----\n🎉   | x   \nab   | y   \n```",
+		},
+		{
+			name: "right to left",
+			in:   "| Name |\n|---|\n| שלום |",
+			want: "```\nName\n----\n‎שלום‎\n```",
+		},
+		{
+			name: "right to left isolates",
+			in:   "| Name |\n|---|\n| שלום |",
+			opts: Options{TableIsolates: true},
+			want: "```\nName\n----\n⁨שלום⁩\n```",
+		},
+		{
+			name: "max cell width",
+			in:   "| Note |\n|---|\n| a rather long note |",
+			opts: Options{MaxCellWidth: 8},
+			want: "```\nNote    \n--------\na rathe…\n```",
+		},
+		{
+			name: "cell footnotes",
+			in:   "| Note |\n|---|\n| a rather long note |",
+			opts: Options{MaxCellWidth: 8, CellFootnotes: true},
+			want: "```\nNote    \n--------\na ra…[1]\n```\n\n[1] a rather long note",
+		},
+		{
+			name: "inside a fence",
+			in:   "```\nName | Qty\n--- | ---\napple | 3\n```",
+			want: "```Name | Qty\n--- | ---\napple | 3\n```",
+		},
+		{
+			name: "inside a tilde fence",
+			in:   "~~~\n| A | B |\n|---|---|\n~~~",
+			want: "~~~\n| A | B |\n|---|---|\n~~~",
+		},
+		{
+			name: "html table",
+			in:   "<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>",
+			want: "```\nA | B\n--|--\n1 | 2\n```",
+		},
+		{
+			name: "grid table",
+			in:   "+---+---+\n| a | b |\n+===+===+\n| 1 | 2 |\n+---+---+",
+			want: "```\na | b\n--|--\n1 | 2\n```",
+		},
+		{
+			name: "grid table multi-line cells",
+			in:   "+----+-------+\n| a  | b     |\n+====+=======+\n| 1  | one   |\n|    | two   |\n|    |       |\n|    | three |\n+----+-------+",
+			want: "```\na | b    \n--|------\n1 | one  \n  | two  \n  |      \n  | three\n```",
+		},
+		{
+			name: "grid table multi-line cells plain",
+			in:   "+----+-------+\n| a  | b     |\n+====+=======+\n| 1  | one   |\n|    | two   |\n+----+-------+",
+			opts: Options{TableMode: "plain"},
+			want: "a | b  \n--|----\n1 | one\n  | two",
+		},
+		{
+			name: "select columns",
+			in:   fruit,
+			opts: Options{TableColumns: []string{"Qty", "name"}},
+			want: "```\nQty   | Name  \n------|-------\n12    | fig   \n3     | Apple \n1,200 | cherry\n```",
+		},
+		{
+			name: "exclude column",
+			in:   fruit,
+			opts: Options{TableColumns: []string{"-2"}},
+			want: "```\nName  \n------\nfig   \nApple \ncherry\n```",
+		},
+		{
+			name: "sort by text",
+			in:   fruit,
+			opts: Options{TableSort: "Name"},
+			want: "```\nName   | Qty  \n-------|------\nApple  | 3    \ncherry | 1,200\nfig    | 12   \n```",
+		},
+		{
+			name: "sort by number descending",
+			in:   fruit,
+			opts: Options{TableSort: "-2"},
+			want: "```\nName   | Qty  \n-------|------\ncherry | 1,200\nfig    | 12   \nApple  | 3    \n```",
+		},
+		{
+			name: "limit",
+			in:   fruit,
+			opts: Options{TableSort: "-Qty", TableLimit: 1},
+			want: "```\nName   | Qty  \n-------|------\ncherry | 1,200\n```\n…and 2 more rows",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.in, tt.opts); got != tt.want {
+				t.Errorf("Convert(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTableRow(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"| a | b |", []string{"a", "b"}},
+		{"a | b", []string{"a", "b"}},
+		{"| a \\| b | c |", []string{"a | b", "c"}},
+		{"| a | b \\|", []string{"a", "b |"}},
+		{"| | b |", []string{"", "b"}},
+	}
+	for _, tt := range tests {
+		got := splitTableRow(tt.line)
+		if len(got) != len(tt.want) {
+			t.Errorf("splitTableRow(%q) = %q, want %q", tt.line, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("splitTableRow(%q) = %q, want %q", tt.line, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCheckTableColumns(t *testing.T) {
+	const table = "| Name | Qty |\n|---|---|\n| a | 1 |"
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"known header", Options{TableSort: "-qty"}, nil},
+		{"known index", Options{TableSort: "2", TableColumns: []string{"1"}}, nil},
+		{"unknown header", Options{TableSort: "Price"}, []string{`1:1: table has no column "Price" to sort by; its rows keep their order`}},
+		{"index out of range", Options{TableSort: "-3"}, []string{`1:1: table has no column "3" to sort by; its rows keep their order`}},
+		{"unknown column", Options{TableColumns: []string{"Name", "-Owner"}}, []string{`1:1: table has no column "Owner" to show or leave out`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, w := range Check(table, tt.opts) {
+				if w.Code == WarnTableColumn {
+					got = append(got, w.String())
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Check warnings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package slackify
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// normalizeLineEndings strips a leading byte order mark and converts CRLF and
+// lone CR line endings to LF, so line-anchored patterns see clean lines
+func normalizeLineEndings(text string) string {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// Patterns for the whitespace compactSpacing removes
+var (
+	trailingSpaceRegex = regexp.MustCompile(`(?m)[ \t]+$`)
+	blankRunRegex      = regexp.MustCompile(`\n{3,}`)
+)
+
+// appendSection adds section to the end of text after a blank line. The blank
+// lines ending text become that one, unless preserve is set: then they are
+// kept, and a blank line is only added when there is none.
+func appendSection(text, section string, preserve bool) string {
+	if preserve && strings.HasSuffix(text, "\n\n") {
+		return text + section
+	}
+	if preserve && strings.HasSuffix(text, "\n") {
+		return text + "\n" + section
+	}
+	return strings.TrimRight(text, "\n") + "\n\n" + section
+}
+
+// compactSpacing trims trailing whitespace and collapses runs of blank lines
+// into a single one, leaving code blocks as they are
+func compactSpacing(text string) string {
+	text = mapOutsideCode(text, func(s string) string {
+		s = trailingSpaceRegex.ReplaceAllString(s, "")
+		return blankRunRegex.ReplaceAllString(s, "\n\n")
+	})
+	return strings.Trim(text, "\n")
+}
+
+// hardBreakRegex finds lines ending in a hard break
+var hardBreakRegex = regexp.MustCompile(`(?m)([^\s\\])(?: {2,}|\\)$`)
+
+// convertHardBreaks drops the markdown hard line break markers (two trailing
+// spaces or a trailing backslash); the newline itself already is a real break
+// in Slack
+func convertHardBreaks(text string) string {
+	return mapOutsideCode(text, func(s string) string {
+		return hardBreakRegex.ReplaceAllString(s, "$1")
+	})
+}
+
+// Patterns for the quirks of Notion's markdown export
+var (
+	notionToggleRegex     = regexp.MustCompile(`(?s)<details>\s*<summary>(.*?)</summary>(.*?)</details>`)
+	notionHeadingTagRegex = regexp.MustCompile(`</?h[1-6]>`)
+	notionDatabaseRegex   = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\.csv\)`)
+	notionLinkTargetRegex = regexp.MustCompile(`\]\(([^)]+)\)`)
+	notionBlockIDRegex    = regexp.MustCompile(`(?:%20| |-)[0-9a-f]{32}\b`)
+	notionPVSRegex        = regexp.MustCompile(`\?pvs=\d+`)
+	notionListItemRegex   = regexp.MustCompile(`^\s*([-*+]|\d+\.)\s`)
+)
+
+// normalizeNotion cleans up Notion's markdown export: toggles exported as
+// <details> become a bold title followed by their content, links to inline
+// databases (CSV files) become labelled text, block IDs are stripped from
+// exported file links and the blank lines Notion puts between list items are
+// removed, unless preserve is set
+func normalizeNotion(text string, preserve bool) string {
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	text = notionToggleRegex.ReplaceAllStringFunc(text, func(m string) string {
+		parts := notionToggleRegex.FindStringSubmatch(m)
+		title := strings.TrimSpace(notionHeadingTagRegex.ReplaceAllString(parts[1], ""))
+		return "**" + title + "**\n\n" + strings.TrimSpace(parts[2])
+	})
+
+	text = notionDatabaseRegex.ReplaceAllString(text, "$1 (Notion database)")
+	text = notionLinkTargetRegex.ReplaceAllStringFunc(text, func(m string) string {
+		// notion.so URLs need their ID to resolve; only exported file names lose it
+		if strings.Contains(m, "://") {
+			return notionPVSRegex.ReplaceAllString(m, "")
+		}
+		return notionPVSRegex.ReplaceAllString(notionBlockIDRegex.ReplaceAllString(m, ""), "")
+	})
+
+	// Drop blank lines that only separate two list items
+	lines := strings.Split(text, "\n")
+	result := []string{}
+	for i, line := range lines {
+		if !preserve && strings.TrimSpace(line) == "" && i > 0 && i+1 < len(lines) &&
+			notionListItemRegex.MatchString(lines[i-1]) && notionListItemRegex.MatchString(lines[i+1]) {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// Patterns for the lines that start blocks or end with a hard break
+var (
+	fenceLineRegex     = regexp.MustCompile("^\\s*(```|~~~)")
+	listItemRegex      = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s`)
+	trailingBreakRegex = regexp.MustCompile(`\S( {2,}|\\)$`)
+)
+
+// reflowParagraphs joins soft-wrapped lines of a paragraph, list item or
+// blockquote into a single line. Code, tables, headings and other block-level
+// lines are never joined.
+func reflowParagraphs(text string) string {
+	lines := strings.Split(text, "\n")
+	result := []string{}
+	inFence := false
+	joinable := false // whether the last output line may take a continuation
+
+	join := func(continuation string) {
+		last := len(result) - 1
+		result[last] = strings.TrimRight(result[last], " ") + " " + continuation
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case fenceLineRegex.MatchString(line):
+			inFence = !inFence
+			result = append(result, line)
+			joinable = false
+		case inFence, trimmed == "":
+			result = append(result, line)
+			joinable = false
+		case !joinable && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			// Indented code block
+			result = append(result, line)
+		case strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "<"),
+			strings.Contains(trimmed, "|"), ruleRegex.MatchString(trimmed):
+			// Headings, HTML, table rows and rules stand on their own line
+			result = append(result, line)
+			joinable = false
+		case listItemRegex.MatchString(line):
+			result = append(result, line)
+			joinable = true
+		case strings.HasPrefix(trimmed, ">"):
+			quoted := strings.TrimSpace(trimmed[1:])
+			if joinable && quoted != "" && strings.HasPrefix(strings.TrimSpace(result[len(result)-1]), ">") {
+				join(quoted)
+			} else {
+				result = append(result, line)
+				joinable = quoted != ""
+			}
+		case joinable:
+			join(trimmed)
+		default:
+			result = append(result, line)
+			joinable = true
+		}
+
+		// A hard line break ends the line even inside a paragraph
+		if trailingBreakRegex.MatchString(line) {
+			joinable = false
+		}
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// Patterns for the blocks Slack cannot represent
+var (
+	htmlBlockRegex    = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9-]*)[\s/>]`)
+	nestedQuoteRegex  = regexp.MustCompile(`^\s*>\s*>`)
+	quotedListRegex   = regexp.MustCompile(`^\s*>\s*([-*+]|\d+[.)])\s`)
+	bulletIndentRegex = regexp.MustCompile(`^( *)[-*+]\s`)
+)
+
+// unconvertibleBlocks returns the byte ranges of the blocks Slack cannot show
+// faithfully: raw HTML, nested blockquotes, lists inside quotes and lists
+// nested deeper than levels. Fenced code is skipped.
+func unconvertibleBlocks(text string, levels int) [][]int {
+	deepList := func(line string) bool {
+		m := bulletIndentRegex.FindStringSubmatch(line)
+		return m != nil && len(m[1]) >= 2*levels
+	}
+
+	var blocks [][]int
+	start, end := -1, 0
+	unconvertible := false
+	flush := func() {
+		if start >= 0 && unconvertible {
+			blocks = append(blocks, []int{start, end})
+		}
+		start, unconvertible = -1, false
+	}
+
+	inFence := false
+	offset := 0
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case fenceLineRegex.MatchString(line):
+			flush()
+			inFence = !inFence
+		case inFence:
+		case strings.TrimSpace(line) == "":
+			flush()
+		default:
+			if start < 0 {
+				start = offset
+				if m := htmlBlockRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil && !convertedTags[strings.ToLower(m[1])] {
+					unconvertible = true
+				}
+			}
+			if nestedQuoteRegex.MatchString(line) || quotedListRegex.MatchString(line) || deepList(line) {
+				unconvertible = true
+			}
+			end = offset + len(line)
+		}
+		offset += len(line) + 1
+	}
+	flush()
+
+	return blocks
+}
+
+// codeFallback wraps the blocks unconvertibleBlocks finds in code blocks, so
+// they appear verbatim instead of half converted
+func codeFallback(text string, levels int) string {
+	var b strings.Builder
+	last := 0
+	for _, block := range unconvertibleBlocks(text, levels) {
+		b.WriteString(text[last:block[0]])
+		b.WriteString("```\n" + text[block[0]:block[1]] + "\n```")
+		last = block[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// glossaryRegex compiles a pattern matching the glossary's terms as whole
+// words, longest first so a term wins over the shorter terms inside it
+func glossaryRegex(glossary map[string]string) *regexp.Regexp {
+	terms := slices.Collect(maps.Keys(glossary))
+	slices.SortFunc(terms, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a, b))
+	})
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+		if isWordByte(term[0]) {
+			quoted[i] = `\b` + quoted[i]
+		}
+		if isWordByte(term[len(term)-1]) {
+			quoted[i] += `\b`
+		}
+	}
+	// URLs and Slack links are matched too, so they can be skipped
+	return regexp.MustCompile(`<[^<>\n]*>|https?://\S+|` + strings.Join(quoted, "|"))
+}
+
+// applyGlossary replaces the glossary terms that pattern, from glossaryRegex,
+// finds in text
+func applyGlossary(text string, pattern *regexp.Regexp, glossary map[string]string) string {
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if replacement, ok := glossary[match]; ok {
+			return replacement
+		}
+		return match
+	})
+}
+
+// truncateOutput cuts text down to at most limit characters at a block
+// boundary (a blank line outside code) and appends a continuation notice
+// starting with ellipsis. The result never exceeds limit, even when only
+// part of the notice fits.
+func truncateOutput(text string, limit int, moreURL string, ellipsis string) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	notice := ellipsis
+	if moreURL != "" {
+		notice = fmt.Sprintf("%s full document: <%s>", ellipsis, moreURL)
+	}
+	budget := limit - utf8.RuneCountInString(notice) - 2
+
+	// Find the blank lines that separate blocks, skipping those inside code
+	lines := strings.Split(text, "\n")
+	inCode := false
+	kept := 0
+	length := 0
+	for i, line := range lines {
+		if strings.Count(line, "```")%2 == 1 {
+			inCode = !inCode
+		}
+		length += utf8.RuneCountInString(line) + 1
+		if length > budget {
+			break
+		}
+		if !inCode && strings.TrimSpace(line) == "" {
+			kept = i
+		}
+	}
+
+	// No block fits; fall back to whole lines, then to a hard cut, leaving
+	// room to close a code block the cut lands in
+	if kept == 0 {
+		budget -= len("\n```")
+		length = 0
+		for i, line := range lines {
+			length += utf8.RuneCountInString(line) + 1
+			if length > budget {
+				break
+			}
+			kept = i + 1
+		}
+	}
+
+	var cut string
+	if kept > 0 {
+		cut = strings.Join(lines[:kept], "\n")
+	} else if budget > 0 {
+		cut = string([]rune(text)[:budget])
+	}
+
+	cut = strings.TrimRight(cut, " \t\n")
+	if cut == "" {
+		// Nothing fits beside the notice, so it stands alone, falling back to
+		// the bare ellipsis and then to as much of that as fits
+		if utf8.RuneCountInString(notice) > limit {
+			notice = ellipsis
+		}
+		return string([]rune(notice)[:min(limit, utf8.RuneCountInString(notice))])
+	}
+	if strings.Count(cut, "```")%2 == 1 {
+		cut += "\n```"
+	}
+	return cut + "\n\n" + notice
+}
+
+// emojiRegex finds Unicode emoji, with their variation selectors, skin tones
+// and zero-width joiner sequences, keycaps and :emoji: codes
+var emojiRegex = regexp.MustCompile(`(?:[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{2B1B}\x{2B1C}\x{2B50}\x{2B55}\x{3030}\x{303D}]` +
+	`[\x{FE0F}\x{1F3FB}-\x{1F3FF}\x{E0020}-\x{E007F}]*\x{200D}?)+|[#*0-9]\x{FE0F}?\x{20E3}|` + shortcodePattern)
+
+// stripEmoji removes the emoji from text, with a space next to one when
+// that would leave two in a row or one at the start or end of a line
+func stripEmoji(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range emojiRegex.FindAllStringIndex(text, -1) {
+		if loc[0] < last {
+			continue
+		}
+		start, end := loc[0], loc[1]
+		before := b.Len() == 0 && last == start || start > 0 && (text[start-1] == ' ' || text[start-1] == '\n')
+		if before && end < len(text) && text[end] == ' ' {
+			end++
+		} else if start > last && text[start-1] == ' ' && (end == len(text) || text[end] == '\n') {
+			start--
+		}
+		b.WriteString(text[last:start])
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
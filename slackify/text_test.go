@@ -0,0 +1,83 @@
+package slackify
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPreserveSpacing(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		opts    Options
+		want    string
+		changed string
+	}{
+		{
+			name:    "notion list items",
+			in:      "- one\n\n- two",
+			opts:    Options{Dialect: "notion"},
+			want:    "• one\n• two",
+			changed: "• one\n\n• two",
+		},
+		{
+			name:    "footnote definition",
+			in:      "Text[^a]\n\n[^a]: note\n\nMore\n\n",
+			want:    "Text[1]\n\nMore\n\n[1] note\n",
+			changed: "Text[1]\n\n\nMore\n\n[1] note\n",
+		},
+		{
+			name:    "abbreviation glossary",
+			in:      "The API\n\n\n*[API]: Application Programming Interface",
+			opts:    Options{Abbreviations: "glossary"},
+			want:    "The API\n\n*Abbreviations*\n\n• API: Application Programming Interface",
+			changed: "The API\n\n\n*Abbreviations*\n\n• API: Application Programming Interface",
+		},
+		{
+			name:    "blank line inside a table",
+			in:      "| A |\n|---|\n| 1 |\n\n| 2 |",
+			want:    "```\nA\n-\n1\n2\n```",
+			changed: "```\nA\n-\n1\n```\n\n| 2 |",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.in, tt.opts); got != tt.want {
+				t.Errorf("Convert(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			tt.opts.PreserveSpacing = true
+			if got := Convert(tt.in, tt.opts); got != tt.changed {
+				t.Errorf("Convert(%q) preserving spacing = %q, want %q", tt.in, got, tt.changed)
+			}
+		})
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	const text = "First paragraph.\n\nSecond paragraph is longer.\n\nThird."
+	tests := []struct {
+		name    string
+		limit   int
+		moreURL string
+		want    string
+	}{
+		{"fits", 100, "", text},
+		{"at a block", 30, "", "First paragraph.\n\n…"},
+		{"with link", 52, "https://x.io", "First paragraph.\n\n… full document: <https://x.io>"},
+		{"hard cut", 10, "", "Fir\n\n…"},
+		{"only the notice", 3, "", "…"},
+		{"only the ellipsis", 5, "https://example.com/doc", "…"},
+		{"one character", 1, "", "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateOutput(text, tt.limit, tt.moreURL, "…")
+			if got != tt.want {
+				t.Errorf("truncateOutput(%d) = %q, want %q", tt.limit, got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.limit {
+				t.Errorf("truncateOutput(%d) is %d characters", tt.limit, n)
+			}
+		})
+	}
+}
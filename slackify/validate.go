@@ -0,0 +1,216 @@
+package slackify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Block Kit limits, as documented by Slack
+const (
+	MaxBlocks        = 50
+	sectionTextLimit = 3000
+	sectionFields    = 10
+	fieldTextLimit   = 2000
+	headerTextLimit  = 150
+	imageURLLimit    = 3000
+	altTextLimit     = 2000
+	contextElements  = 10
+	actionsElements  = 25
+)
+
+// blockTypes are the block types Slack accepts in messages; input blocks
+// belong to modals and App Home, not messages
+var blockTypes = map[string]bool{
+	"section": true, "divider": true, "image": true, "actions": true, "context": true,
+	"header": true, "rich_text": true, "file": true, "video": true,
+}
+
+// richTextTypes are the element types a rich_text block may hold, and
+// richTextInlineTypes those its sections, lists and quotes may hold
+var (
+	richTextTypes = map[string]bool{
+		"rich_text_section": true, "rich_text_list": true, "rich_text_preformatted": true, "rich_text_quote": true,
+	}
+	richTextInlineTypes = map[string]bool{
+		"text": true, "link": true, "emoji": true, "user": true, "channel": true,
+		"usergroup": true, "broadcast": true, "date": true, "color": true,
+	}
+)
+
+// BlockError is one way a Block Kit payload breaks Slack's rules, with the
+// path to the offending value, like blocks[3].text.text
+type BlockError struct {
+	Path    string
+	Message string
+}
+
+// Error formats the error as path: message
+func (e BlockError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// BlockErrors lists every problem found in a payload
+type BlockErrors []BlockError
+
+// Error joins the problems into one line
+func (e BlockErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateBlocks checks a Block Kit payload, either {"blocks": [...]} or a
+// bare array of blocks, against Slack's block types and size limits. It
+// returns BlockErrors describing every problem, or nil.
+func ValidateBlocks(payload string) error {
+	var blocks []map[string]any
+	if strings.HasPrefix(strings.TrimSpace(payload), "[") {
+		if err := json.Unmarshal([]byte(payload), &blocks); err != nil {
+			return err
+		}
+	} else {
+		var doc struct {
+			Blocks []map[string]any `json:"blocks"`
+		}
+		if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+			return err
+		}
+		blocks = doc.Blocks
+	}
+
+	v := &blockValidator{}
+	if len(blocks) > MaxBlocks {
+		v.fail("blocks", "%d blocks, over Slack's limit of %d per message", len(blocks), MaxBlocks)
+	}
+	for i, b := range blocks {
+		v.block(fmt.Sprintf("blocks[%d]", i), b)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// blockValidator collects the problems found while walking a payload
+type blockValidator struct {
+	errs BlockErrors
+}
+
+// fail records a problem at path
+func (v *blockValidator) fail(path string, format string, args ...any) {
+	v.errs = append(v.errs, BlockError{path, fmt.Sprintf(format, args...)})
+}
+
+// length checks that the string at key is present, when required, and no
+// longer than limit characters
+func (v *blockValidator) length(path string, m map[string]any, key string, limit int, required bool) {
+	s, ok := m[key].(string)
+	switch {
+	case !ok || s == "":
+		if required {
+			v.fail(path+"."+key, "is required")
+		}
+	case utf8.RuneCountInString(s) > limit:
+		v.fail(path+"."+key, "%d characters, over the limit of %d", utf8.RuneCountInString(s), limit)
+	}
+}
+
+// textObject checks the text object at key, which must be plain_text when
+// plainOnly is set
+func (v *blockValidator) textObject(path string, m map[string]any, key string, limit int, required bool, plainOnly bool) {
+	obj, ok := m[key].(map[string]any)
+	if !ok {
+		if required {
+			v.fail(path+"."+key, "is required")
+		}
+		return
+	}
+	path += "." + key
+	switch obj["type"] {
+	case "plain_text":
+	case "mrkdwn":
+		if plainOnly {
+			v.fail(path+".type", "must be plain_text")
+		}
+	default:
+		v.fail(path+".type", "must be plain_text or mrkdwn, not %v", obj["type"])
+	}
+	v.length(path, obj, "text", limit, true)
+}
+
+// list returns the array at key, checking it holds at most limit items
+func (v *blockValidator) list(path string, m map[string]any, key string, limit int) []any {
+	items, _ := m[key].([]any)
+	if limit > 0 && len(items) > limit {
+		v.fail(path+"."+key, "%d items, over the limit of %d", len(items), limit)
+	}
+	return items
+}
+
+// block checks one block
+func (v *blockValidator) block(path string, b map[string]any) {
+	kind, _ := b["type"].(string)
+	if !blockTypes[kind] {
+		v.fail(path+".type", "unknown block type %q", kind)
+		return
+	}
+
+	switch kind {
+	case "section":
+		fields := v.list(path, b, "fields", sectionFields)
+		v.textObject(path, b, "text", sectionTextLimit, len(fields) == 0, false)
+		for i, field := range fields {
+			if obj, ok := field.(map[string]any); ok {
+				v.length(fmt.Sprintf("%s.fields[%d]", path, i), obj, "text", fieldTextLimit, true)
+			}
+		}
+	case "header":
+		v.textObject(path, b, "text", headerTextLimit, true, true)
+	case "image":
+		v.length(path, b, "image_url", imageURLLimit, true)
+		v.length(path, b, "alt_text", altTextLimit, true)
+		v.textObject(path, b, "title", altTextLimit, false, true)
+	case "context":
+		v.list(path, b, "elements", contextElements)
+	case "actions":
+		v.list(path, b, "elements", actionsElements)
+	case "rich_text":
+		for i, element := range v.list(path, b, "elements", 0) {
+			v.richText(fmt.Sprintf("%s.elements[%d]", path, i), element, richTextTypes)
+		}
+	}
+}
+
+// richText checks a rich text element and the elements it holds
+func (v *blockValidator) richText(path string, element any, types map[string]bool) {
+	e, ok := element.(map[string]any)
+	if !ok {
+		v.fail(path, "must be an object")
+		return
+	}
+	kind, _ := e["type"].(string)
+	if !types[kind] {
+		v.fail(path+".type", "%q is not allowed here", kind)
+		return
+	}
+
+	switch kind {
+	case "rich_text_list":
+		for i, item := range v.list(path, e, "elements", 0) {
+			v.richText(fmt.Sprintf("%s.elements[%d]", path, i), item, map[string]bool{"rich_text_section": true})
+		}
+	case "rich_text_section", "rich_text_preformatted", "rich_text_quote":
+		for i, inline := range v.list(path, e, "elements", 0) {
+			v.richText(fmt.Sprintf("%s.elements[%d]", path, i), inline, richTextInlineTypes)
+		}
+	case "text":
+		v.length(path, e, "text", MessageHardLimit, true)
+	case "link":
+		v.length(path, e, "url", imageURLLimit, true)
+	}
+}
@@ -0,0 +1,27 @@
+package slackify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBlocksTypes(t *testing.T) {
+	tests := []struct {
+		payload string
+		err     string
+	}{
+		{`{"blocks": [{"type": "divider"}]}`, ""},
+		{`[{"type": "divider"}, {"type": "header", "text": {"type": "plain_text", "text": "Hi"}}]`, ""},
+		{`[{"type": "input", "label": {"type": "plain_text", "text": "Name"}, "element": {"type": "plain_text_input"}}]`, `unknown block type "input"`},
+		{`[{"type": "carousel"}]`, `unknown block type "carousel"`},
+	}
+	for _, tt := range tests {
+		err := ValidateBlocks(tt.payload)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("ValidateBlocks(%s) = %v, want no error", tt.payload, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("ValidateBlocks(%s) = %v, want an error containing %q", tt.payload, err, tt.err)
+		}
+	}
+}
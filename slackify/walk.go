@@ -0,0 +1,238 @@
+package slackify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Node is a block or inline construct of a markdown document, as Walk finds
+// it. Blocks are "heading", "paragraph", "list item", "quote", "code block",
+// "table" and "rule"; inside headings, paragraphs, list items, quotes and
+// tables are the inline "link", "image", "autolink" and "code" nodes.
+type Node struct {
+	Kind string
+	// Text is the node's markdown. Setting it rewrites the node.
+	Text string
+	// Line and Column are where the node starts, 1-based
+	Line, Column int
+
+	// Level is a heading's level, from 1 to 6
+	Level int
+	// Label is the text of a link or the alt text of an image, and URL
+	// their target
+	Label, URL string
+	// Lang is the language of a fenced code block
+	Lang string
+}
+
+// WalkAction tells Walk what to do after visiting a node
+type WalkAction int
+
+// Walk actions
+const (
+	// WalkContinue goes on to the node's children and the nodes after it
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren goes on without visiting the node's inline children
+	WalkSkipChildren
+	// WalkRemove drops the node from the document
+	WalkRemove
+	// WalkStop visits no more nodes, keeping the rest of the document as is
+	WalkStop
+)
+
+// Patterns for the nodes Walk finds
+var (
+	walkFenceRegex   = regexp.MustCompile("^[ \t]*(```|~~~)[ \t]*([\\w+-]*)")
+	walkHeadingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+|$)(.*?)[ \t#]*$`)
+	walkInlineRegex  = regexp.MustCompile("`[^`\n]+`" +
+		`|!\[([^\]]*)\]\(([^)\s]+)[^)]*\)` +
+		`|\[([^\]]+)\]\(([^)\s]+)[^)]*\)` +
+		`|<((?:https?://|mailto:)[^>\s|]+)>`)
+)
+
+// Walk calls fn for each node of markdown text in document order, every
+// block before its inline children, and returns the document with the
+// changes fn made: nodes whose Text it set are rewritten and nodes it
+// removed are dropped, along with the blank lines after a removed block.
+// Children of a block whose Text changed are not visited.
+//
+// Walk lets programs inspect or rewrite a document before converting it,
+// like collecting all links or removing all images.
+func Walk(text string, fn func(*Node) WalkAction) string {
+	var b strings.Builder
+	last := 0
+	stopped := false
+	for _, span := range walkBlocks(text) {
+		b.WriteString(text[last:span.start])
+		last = span.end
+		if stopped {
+			b.WriteString(text[span.start:span.end])
+			continue
+		}
+
+		node := &Node{Kind: span.kind, Text: text[span.start:span.end], Level: span.level, Lang: span.lang}
+		node.Line, node.Column = position(text, span.start)
+		action := fn(node)
+		switch {
+		case action == WalkRemove:
+			// Take the blank lines after the block with it
+			for last < len(text) && (text[last] == '\n' || text[last] == ' ' || text[last] == '\t') {
+				last++
+			}
+		case action == WalkStop:
+			b.WriteString(node.Text)
+			stopped = true
+		case action == WalkSkipChildren || node.Text != text[span.start:span.end] || !span.inline:
+			b.WriteString(node.Text)
+		default:
+			var stop bool
+			b.WriteString(walkInline(text, span.start, span.end, fn, &stop))
+			stopped = stop
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// walkInline calls fn for the inline nodes in text[start:end], returning
+// that part of text rewritten. stop is set when fn returns WalkStop.
+func walkInline(text string, start, end int, fn func(*Node) WalkAction, stop *bool) string {
+	block := text[start:end]
+	var b strings.Builder
+	last := 0
+	for _, loc := range walkInlineRegex.FindAllStringSubmatchIndex(block, -1) {
+		b.WriteString(block[last:loc[0]])
+		last = loc[1]
+		if *stop {
+			b.WriteString(block[loc[0]:loc[1]])
+			continue
+		}
+
+		node := &Node{Text: block[loc[0]:loc[1]]}
+		node.Line, node.Column = position(text, start+loc[0])
+		switch {
+		case loc[2] >= 0 || loc[4] >= 0:
+			node.Kind, node.Label, node.URL = "image", block[loc[2]:loc[3]], block[loc[4]:loc[5]]
+		case loc[6] >= 0:
+			node.Kind, node.Label, node.URL = "link", block[loc[6]:loc[7]], block[loc[8]:loc[9]]
+		case loc[10] >= 0:
+			node.Kind, node.URL = "autolink", block[loc[10]:loc[11]]
+		default:
+			node.Kind = "code"
+		}
+
+		switch fn(node) {
+		case WalkRemove:
+		case WalkStop:
+			*stop = true
+			b.WriteString(node.Text)
+		default:
+			b.WriteString(node.Text)
+		}
+	}
+	b.WriteString(block[last:])
+	return b.String()
+}
+
+// blockSpan is a block of a document at text[start:end], without its final
+// newline. inline tells whether it holds inline nodes.
+type blockSpan struct {
+	kind       string
+	start, end int
+	level      int
+	lang       string
+	inline     bool
+}
+
+// walkBlocks splits text into blocks: fenced code to its closing fence,
+// headings and rules by line, runs of quote or table lines, list items with
+// their indented continuation lines, and paragraphs up to a blank line or the
+// start of another block
+func walkBlocks(text string) []blockSpan {
+	var spans []blockSpan
+	var lines []string
+	var offsets []int
+	offset := 0
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, line)
+		offsets = append(offsets, offset)
+		offset += len(line) + 1
+	}
+	lineEnd := func(i int) int {
+		return offsets[i] + len(lines[i])
+	}
+	isQuote := func(line string) bool {
+		return strings.HasPrefix(strings.TrimSpace(line), ">")
+	}
+	// isTable reports whether line i starts a table the converter would
+	// format: a row with outer pipes, or a header row followed by its
+	// delimiter row
+	isTable := func(i int) bool {
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+		return strings.HasPrefix(strings.TrimSpace(lines[i]), "|") || isTableStart(lines[i], next)
+	}
+	startsBlock := func(i int) bool {
+		line := lines[i]
+		return walkFenceRegex.MatchString(line) || walkHeadingRegex.MatchString(line) ||
+			ruleRegex.MatchString(strings.TrimSpace(line)) || isQuote(line) || isTable(i) || listItemRegex.MatchString(line)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		span := blockSpan{start: offsets[i]}
+
+		switch {
+		case trimmed == "":
+			continue
+
+		case walkFenceRegex.MatchString(line):
+			m := walkFenceRegex.FindStringSubmatch(line)
+			span.kind, span.lang = "code block", m[2]
+			j := i + 1
+			for j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), m[1]) {
+				j++
+			}
+			i = min(j, len(lines)-1)
+
+		case walkHeadingRegex.MatchString(line):
+			span.kind, span.inline = "heading", true
+			span.level = len(walkHeadingRegex.FindStringSubmatch(line)[1])
+
+		case ruleRegex.MatchString(trimmed):
+			span.kind = "rule"
+
+		case isQuote(line):
+			span.kind, span.inline = "quote", true
+			for i+1 < len(lines) && isQuote(lines[i+1]) {
+				i++
+			}
+
+		case isTable(i):
+			span.kind, span.inline = "table", true
+			for i+1 < len(lines) && strings.Contains(lines[i+1], "|") {
+				i++
+			}
+
+		case listItemRegex.MatchString(line):
+			span.kind, span.inline = "list item", true
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && !listItemRegex.MatchString(lines[i+1]) &&
+				len(lines[i+1])-len(strings.TrimLeft(lines[i+1], " \t")) > indent {
+				i++
+			}
+
+		default:
+			span.kind, span.inline = "paragraph", true
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && !startsBlock(i+1) {
+				i++
+			}
+		}
+		span.end = lineEnd(i)
+		spans = append(spans, span)
+	}
+	return spans
+}
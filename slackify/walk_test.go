@@ -0,0 +1,32 @@
+package slackify
+
+import "testing"
+
+func TestWalkTables(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     []string
+	}{
+		{"outer pipes", "| a | b |\n|---|---|\n| 1 | 2 |", []string{"table"}},
+		{"without outer pipes", "a | b\n--- | ---\n1 | 2", []string{"table"}},
+		{"after a paragraph", "Intro\na | b\n--- | ---\n1 | 2\n\nAfter", []string{"paragraph", "table", "paragraph"}},
+		{"pipe in a paragraph", "a | b\nc", []string{"paragraph"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			Walk(tt.in, func(n *Node) WalkAction {
+				got = append(got, n.Kind)
+				return WalkSkipChildren
+			})
+			if len(got) != len(tt.want) {
+				t.Fatalf("Walk(%q) kinds = %q, want %q", tt.in, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Walk(%q) kinds = %q, want %q", tt.in, got, tt.want)
+				}
+			}
+		})
+	}
+}
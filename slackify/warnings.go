@@ -0,0 +1,184 @@
+package slackify
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Warning points at a construct in the input that the conversion drops or
+// cannot represent in Slack
+type Warning struct {
+	// Code names the kind of problem, one of the Warn constants, for
+	// programs to act on; Message describes it for people
+	Code string
+	// Line and Column are 1-based; columns count characters, not bytes
+	Line    int
+	Column  int
+	Message string
+}
+
+// Warning codes
+const (
+	WarnUnclosedCode = "unclosed-code"
+	WarnImage        = "image"
+	WarnFootnote     = "footnote"
+	WarnHTML         = "html"
+	WarnDeepHeading  = "deep-heading"
+	WarnDeepList     = "deep-list"
+	WarnSecret       = "secret"
+	WarnTableColumn  = "table-column"
+
+	// Codes of Lint, for text already written as mrkdwn
+	WarnUnbalanced = "unbalanced"
+	WarnUnescaped  = "unescaped"
+	WarnMention    = "mention"
+	WarnTooLong    = "too-long"
+)
+
+// String formats the warning as line:column: message
+func (w Warning) String() string {
+	return fmt.Sprintf("%d:%d: %s", w.Line, w.Column, w.Message)
+}
+
+// position returns the 1-based line and column of a byte offset in text
+func position(text string, offset int) (int, int) {
+	offset = min(offset, len(text))
+	line := strings.Count(text[:offset], "\n") + 1
+	lineStart := strings.LastIndex(text[:offset], "\n") + 1
+	return line, utf8.RuneCountInString(text[lineStart:offset]) + 1
+}
+
+// convertedTags are the HTML tags the conversion understands
+var convertedTags = map[string]bool{
+	"kbd": true, "sup": true, "sub": true, "details": true, "summary": true, "table": true,
+}
+
+// Patterns for the constructs Check warns about
+var (
+	fenceMarkerRegex = regexp.MustCompile("(?m)^[ \t]*```")
+	imageRegex       = regexp.MustCompile(`!\[([^\]]*)\]\([^)]+\)`)
+	footnoteRegex    = regexp.MustCompile(`\[\^[^\]\s]+\]`)
+	htmlTagRegex     = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)(?:\s[^<>]*)?/?>`)
+	deepHeadingRegex = regexp.MustCompile(`(?m)^#{4,6} `)
+	bulletRegex      = regexp.MustCompile(`(?m)^( *)- `)
+)
+
+// Check reports the constructs in markdown text that Convert with opts drops
+// or cannot represent, with their position in text as given
+func Check(text string, opts Options) []Warning {
+	levels := len(opts.Bullets)
+	if levels == 0 {
+		levels = 2
+	}
+
+	// Blocks shown verbatim by CodeFallback or slackify:off count as code too
+	code := codeRegex.FindAllStringIndex(text, -1)
+	if opts.CodeFallback {
+		code = append(code, unconvertibleBlocks(text, levels)...)
+	}
+	code = append(code, passthroughRegex.FindAllStringIndex(text, -1)...)
+	inCode := func(offset int) bool {
+		for _, loc := range code {
+			if offset >= loc[0] && offset < loc[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var warnings []Warning
+	warn := func(offset int, code string, format string, args ...any) {
+		line, column := position(text, offset)
+		warnings = append(warnings, Warning{code, line, column, fmt.Sprintf(format, args...)})
+	}
+
+	if fences := fenceMarkerRegex.FindAllStringIndex(text, -1); len(fences)%2 == 1 {
+		warn(fences[len(fences)-1][0], WarnUnclosedCode, "code block is never closed")
+	}
+
+	for _, loc := range imageRegex.FindAllStringSubmatchIndex(text, -1) {
+		if !inCode(loc[0]) {
+			warn(loc[0], WarnImage, "image %q cannot be embedded in a message; only its link is kept", text[loc[2]:loc[3]])
+		}
+	}
+
+	if !gfmEnabled(opts.GFM, "footnotes") {
+		for _, loc := range footnoteRegex.FindAllStringIndex(text, -1) {
+			if !inCode(loc[0]) {
+				warn(loc[0], WarnFootnote, "footnote %s stays as written with the footnotes extension off", text[loc[0]:loc[1]])
+			}
+		}
+	}
+
+	// Tags inside HTML tables are converted along with the table
+	tables := htmlTableSpans(text)
+	inTable := func(offset int) bool {
+		return slices.ContainsFunc(tables, func(span []int) bool {
+			return offset >= span[0] && offset < span[1]
+		})
+	}
+
+	for _, loc := range htmlTagRegex.FindAllStringSubmatchIndex(text, -1) {
+		tag := strings.ToLower(text[loc[2]:loc[3]])
+		if inTable(loc[0]) {
+			continue
+		}
+		// <details> is only converted when spoilers are
+		spoiler := tag == "details" || tag == "summary"
+		if inCode(loc[0]) || (convertedTags[tag] && (!spoiler || opts.Spoilers != "")) || (opts.Dialect == "notion" && len(tag) == 2 && tag[0] == 'h') {
+			continue
+		}
+		// Tables break cells into lines at <br>
+		lineStart := strings.LastIndexByte(text[:loc[0]], '\n') + 1
+		lineEnd := strings.IndexByte(text[loc[0]:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text) - loc[0]
+		}
+		if tag == "br" && strings.Contains(text[lineStart:loc[0]+lineEnd], "|") {
+			continue
+		}
+		warn(loc[0], WarnHTML, "HTML tag <%s> is not converted and shows as text", tag)
+	}
+
+	for _, loc := range deepHeadingRegex.FindAllStringIndex(text, -1) {
+		if !inCode(loc[0]) && !opts.CommonMarkBlocks {
+			warn(loc[0], WarnDeepHeading, "only three heading levels are converted; this one stays as written")
+		}
+	}
+
+	for _, loc := range bulletRegex.FindAllStringSubmatchIndex(text, -1) {
+		if !inCode(loc[0]) && loc[3]-loc[2] >= 2*levels {
+			warn(loc[0], WarnDeepList, "list item nested deeper than %d levels keeps its - marker", levels)
+		}
+	}
+
+	// Tables need the columns they are sorted by and shown with
+	if opts.TableSort != "" || len(opts.TableColumns) > 0 {
+		lines := strings.Split(text, "\n")
+		offset := 0
+		for i, line := range lines {
+			if i+1 < len(lines) && !inCode(offset) && isTableStart(strings.TrimSpace(line), lines[i+1]) {
+				header := splitTableRow(line)
+				if sort := strings.TrimPrefix(strings.TrimSpace(opts.TableSort), "-"); sort != "" && tableColumn(header, sort) < 0 {
+					warn(offset, WarnTableColumn, "table has no column %q to sort by; its rows keep their order", sort)
+				}
+				for _, column := range opts.TableColumns {
+					column = strings.TrimPrefix(strings.TrimSpace(column), "-")
+					if tableColumn(header, column) < 0 {
+						warn(offset, WarnTableColumn, "table has no column %q to show or leave out", column)
+					}
+				}
+			}
+			offset += len(line) + 1
+		}
+	}
+
+	slices.SortStableFunc(warnings, func(a, b Warning) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return warnings
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/robmathews/slackify-markdown/slackify"
+)
+
+// blockKinds are the kinds of markdown block --stats counts, in report order
+var blockKinds = []string{"heading", "paragraph", "list", "quote", "code block", "table"}
+
+// listRegex finds list items, ordered or not
+var listRegex = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s`)
+
+// countBlocks counts the markdown blocks of each kind in text
+func countBlocks(text string) map[string]int {
+	counts := map[string]int{}
+	previous := ""
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inCode {
+			if strings.HasPrefix(trimmed, "```") {
+				inCode = false
+			}
+			continue
+		}
+
+		kind := ""
+		switch {
+		case trimmed == "":
+			previous = ""
+			continue
+		case strings.HasPrefix(trimmed, "```"):
+			inCode = true
+			kind = "code block"
+		case strings.HasPrefix(trimmed, "#"):
+			kind = "heading"
+		case strings.HasPrefix(trimmed, "|"):
+			kind = "table"
+		case strings.HasPrefix(trimmed, ">"):
+			kind = "quote"
+		case listRegex.MatchString(line):
+			kind = "list"
+		case previous != "":
+			// A continuation line belongs to the block above
+			continue
+		default:
+			kind = "paragraph"
+		}
+
+		// Headings and code blocks always start a new block
+		if kind != previous || kind == "heading" || kind == "code block" {
+			counts[kind]++
+		}
+		previous = kind
+	}
+	return counts
+}
+
+// writeStats reports the size of the converted output and how it fits
+// Slack's message limits
+func writeStats(w io.Writer, markdown []string, converted []string) {
+	output := strings.Join(converted, "")
+	characters := utf8.RuneCountInString(output)
+
+	blocks := map[string]int{}
+	for _, text := range markdown {
+		for kind, n := range countBlocks(text) {
+			blocks[kind] += n
+		}
+	}
+	total := 0
+	breakdown := []string{}
+	for _, kind := range blockKinds {
+		if n := blocks[kind]; n > 0 {
+			total += n
+			plural := "s"
+			if n == 1 {
+				plural = ""
+			}
+			breakdown = append(breakdown, fmt.Sprintf("%d %s%s", n, kind, plural))
+		}
+	}
+
+	messages := 0
+	longest := 0
+	for _, text := range converted {
+		messages += len(slackify.Split(text, slackify.MessageLimit))
+		longest = max(longest, utf8.RuneCountInString(text))
+	}
+
+	fmt.Fprintf(w, "Characters: %d\n", characters)
+	fmt.Fprintf(w, "Words:      %d\n", len(strings.Fields(output)))
+	fmt.Fprintf(w, "Blocks:     %d", total)
+	if len(breakdown) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(breakdown, ", "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Messages:   %d of up to %d characters\n", messages, slackify.MessageLimit)
+
+	switch {
+	case longest > slackify.MessageHardLimit:
+		fmt.Fprintf(w, "Slack:      too long; Slack cuts messages off at %d characters, so split it or use a canvas\n", slackify.MessageHardLimit)
+	case messages > len(converted):
+		fmt.Fprintf(w, "Slack:      over the %d-character message limit; post it as a thread of %d messages\n", slackify.MessageLimit, messages)
+	default:
+		fmt.Fprintf(w, "Slack:      fits in a single message\n")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// slackAPI is the base URL of Slack's Web API
+const slackAPI = "https://slack.com/api/"
+
+// fetchUsergroups returns the workspace's user groups, by handle, from
+// Slack's usergroups.list method, with the token in SLACK_TOKEN. The token
+// needs the usergroups:read scope.
+func fetchUsergroups() (map[string]string, error) {
+	token := os.Getenv("SLACK_TOKEN")
+	if token == "" {
+		return nil, errors.New("SLACK_TOKEN is not set")
+	}
+	req, err := http.NewRequest(http.MethodGet, slackAPI+"usergroups.list", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Slack returned %s for usergroups.list", resp.Status)
+	}
+	var list struct {
+		OK         bool   `json:"ok"`
+		Error      string `json:"error"`
+		Usergroups []struct {
+			ID     string `json:"id"`
+			Handle string `json:"handle"`
+		} `json:"usergroups"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	// Slack reports failures like a missing scope in the body
+	if !list.OK {
+		return nil, fmt.Errorf("usergroups.list failed: %s", list.Error)
+	}
+
+	groups := map[string]string{}
+	for _, group := range list.Usergroups {
+		groups[strings.ToLower(group.Handle)] = group.ID
+	}
+	return groups, nil
+}
+
+// loadUsergroups reads a file of "handle: ID" lines, in the glossary's
+// format, with or without the @ on handles
+func loadUsergroups(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseGlossary(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	groups := map[string]string{}
+	for handle, id := range entries {
+		groups[strings.ToLower(strings.TrimPrefix(handle, "@"))] = id
+	}
+	return groups, nil
+}
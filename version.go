@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Build metadata, set at link time:
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=abc1234 -X main.date=2024-06-01"
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// latestReleasePath is queried by `version --check-update`
+const latestReleasePath = "/repos/robmathews/slackify-markdown/releases/latest"
+
+// buildInfo returns the version, commit and build date, falling back to the
+// module and VCS information Go records when no link-time values were set
+func buildInfo() (string, string, string) {
+	v, c, d := version, commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = strings.TrimPrefix(info.Main.Version, "v")
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && c == "unknown":
+				c = setting.Value
+			case setting.Key == "vcs.time" && d == "unknown":
+				d = setting.Value
+			}
+		}
+	}
+	return v, c, d
+}
+
+// runVersion implements the version subcommand
+func runVersion(args []string) {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	checkUpdate := flags.Bool("check-update", false, "Check GitHub for a newer release")
+	flags.Parse(args)
+
+	v, c, d := buildInfo()
+	if len(c) > 12 {
+		c = c[:12]
+	}
+	fmt.Printf(tr("slackify-markdown %s (commit %s, built %s)\n"), v, c, d)
+
+	if !*checkUpdate {
+		return
+	}
+
+	latest, err := latestRelease()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, tr("Error checking for updates: %v\n"), err)
+		os.Exit(exitIO)
+	}
+	if v != "dev" && compareVersions(strings.TrimPrefix(latest, "v"), v) > 0 {
+		fmt.Printf(tr("A newer release is available: %s\n"), latest)
+	} else {
+		fmt.Printf(tr("Latest release: %s\n"), latest)
+	}
+}
+
+// latestRelease fetches the tag of the latest published release
+func latestRelease() (string, error) {
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := githubGet(latestReleasePath, &release); err != nil {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1.
+// Pre-release and build suffixes are ignored.
+func compareVersions(a, b string) int {
+	numeric := func(v string) []string {
+		if i := strings.IndexAny(v, "-+"); i >= 0 {
+			v = v[:i]
+		}
+		return strings.Split(v, ".")
+	}
+	partsA, partsB := numeric(a), numeric(b)
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			y, _ = strconv.Atoi(partsB[i])
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.4", "1.2.3", 1},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.2.1", "1.2", 1},
+		{"1.3.0-rc.1", "1.3.0", 0},
+		{"1.3.0+build.5", "1.2.9", 1},
+		{"0.0.0-20240101000000-abcdef123456", "0.1.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}